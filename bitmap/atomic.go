@@ -0,0 +1,134 @@
+package bitmap
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+// AtomicBitmap represents a lock-free fixed-size bitmap
+// Every single-bit operation updates its word with an atomic compare-and-swap loop,
+// so any number of goroutines can mark bits without a global lock
+type AtomicBitmap struct {
+	words []atomic.Uint64
+	size  int64
+}
+
+// NewAtomicBitmap creates a new AtomicBitmap with the given size (in bits)
+func NewAtomicBitmap(size int64) *AtomicBitmap {
+	if size < 0 {
+		panic("bitmap size must be non-negative")
+	}
+
+	return &AtomicBitmap{
+		words: make([]atomic.Uint64, wordCount(size)),
+		size:  size,
+	}
+}
+
+// Set sets the bit at the given index to 1
+func (b *AtomicBitmap) Set(index int64) {
+	b.TestAndSet(index)
+}
+
+// MSet sets multiple bits at the given indexes to 1
+// Each bit is set atomically on its own, concurrent readers may observe a part of the batch
+func (b *AtomicBitmap) MSet(indexes []int64) {
+	for _, index := range indexes {
+		b.validateIndex(index)
+	}
+
+	for _, index := range indexes {
+		b.TestAndSet(index)
+	}
+}
+
+// Clear clears the bit at the given index to 0
+func (b *AtomicBitmap) Clear(index int64) {
+	b.TestAndClear(index)
+}
+
+// TestAndSet sets the bit at the given index to 1 and returns whether it was already set
+// Exactly one of several goroutines racing on the same clear bit gets false
+func (b *AtomicBitmap) TestAndSet(index int64) bool {
+	w, mask := b.locate(index)
+
+	for {
+		old := w.Load()
+		if old&mask != 0 {
+			return true
+		}
+
+		if w.CompareAndSwap(old, old|mask) {
+			return false
+		}
+	}
+}
+
+// TestAndClear clears the bit at the given index to 0 and returns whether it was set
+// Exactly one of several goroutines racing on the same set bit gets true
+func (b *AtomicBitmap) TestAndClear(index int64) bool {
+	w, mask := b.locate(index)
+
+	for {
+		old := w.Load()
+		if old&mask == 0 {
+			return false
+		}
+
+		if w.CompareAndSwap(old, old&^mask) {
+			return true
+		}
+	}
+}
+
+// IsSet checks if the bit at the given index is set to 1
+func (b *AtomicBitmap) IsSet(index int64) bool {
+	w, mask := b.locate(index)
+
+	return w.Load()&mask != 0
+}
+
+// Count returns the number of bits set to 1
+// Each word is loaded atomically but the words are read one after another, so the result is not
+// a point-in-time snapshot while writers are running: bits set or cleared during the call may or
+// may not be counted. Bits that stay unchanged for the whole call are always counted correctly,
+// and the result is exact once all writers have finished.
+func (b *AtomicBitmap) Count() int64 {
+	count := int64(0)
+
+	for i := range b.words {
+		count += int64(bits.OnesCount64(b.words[i].Load()))
+	}
+
+	return count
+}
+
+// Size returns the capacity of the bitmap in bits
+func (b *AtomicBitmap) Size() int64 {
+	return b.size
+}
+
+// Snapshot copies the bits into a new Bitmap, with the same consistency guarantees as Count
+func (b *AtomicBitmap) Snapshot() *Bitmap {
+	ret := NewBitmap(b.size)
+
+	for i := range b.words {
+		ret.words[i] = b.words[i].Load()
+	}
+
+	return ret
+}
+
+// locate returns the word holding index and the mask of the index bit in it
+func (b *AtomicBitmap) locate(index int64) (*atomic.Uint64, uint64) {
+	b.validateIndex(index)
+
+	return &b.words[index/wordSize], 1 << (index % wordSize)
+}
+
+// validateIndex checks if index is within valid range
+func (b *AtomicBitmap) validateIndex(index int64) {
+	if index < 0 || index >= b.size {
+		panic("bitmap index out of range")
+	}
+}
@@ -0,0 +1,111 @@
+package bitmap
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtomicBitmap(t *testing.T) {
+	t.Parallel()
+
+	bm := NewAtomicBitmap(130)
+
+	assert.False(t, bm.TestAndSet(64))
+	assert.True(t, bm.TestAndSet(64))
+	assert.True(t, bm.IsSet(64))
+
+	bm.MSet([]int64{0, 129})
+	assert.Equal(t, int64(3), bm.Count())
+
+	assert.True(t, bm.TestAndClear(64))
+	assert.False(t, bm.TestAndClear(64))
+	assert.False(t, bm.IsSet(64))
+
+	bm.Clear(0)
+	assert.Equal(t, int64(1), bm.Count())
+
+	snapshot := bm.Snapshot()
+	assert.Equal(t, int64(130), snapshot.Size())
+	assert.True(t, snapshot.IsSet(129))
+	assert.Equal(t, int64(1), snapshot.Count())
+
+	assert.Panics(t, func() { bm.Set(130) }, "index out of range")
+	assert.Panics(t, func() { bm.IsSet(-1) }, "index out of range")
+	assert.Panics(t, func() { bm.MSet([]int64{1, 130}) }, "index out of range")
+	assert.False(t, bm.IsSet(1), "mset must validate before writing")
+	assert.Panics(t, func() { NewAtomicBitmap(-1) }, "negative size")
+}
+
+func TestAtomicBitmapConcurrency(t *testing.T) {
+	t.Parallel()
+
+	const (
+		size       = 1000
+		goroutines = 16
+	)
+
+	bm := NewAtomicBitmap(size)
+
+	var (
+		wg   sync.WaitGroup
+		wins atomic.Int64
+	)
+
+	// every bit must be won by exactly one goroutine
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := int64(0); i < size; i++ {
+				if !bm.TestAndSet(i) {
+					wins.Add(1)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, int64(size), wins.Load())
+	assert.Equal(t, int64(size), bm.Count())
+
+	wins.Store(0)
+
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := int64(0); i < size; i++ {
+				if bm.TestAndClear(i) {
+					wins.Add(1)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, int64(size), wins.Load())
+	assert.Equal(t, int64(0), bm.Count())
+}
+
+func BenchmarkAtomicConcurrentAccess(b *testing.B) {
+	bm := NewAtomicBitmap(1024)
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			bm.Set(int64(i) % bm.Size())
+			bm.IsSet(int64(i) % bm.Size())
+
+			i++
+		}
+	})
+}
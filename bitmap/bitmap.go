@@ -1,36 +1,58 @@
-// Package bitmap provides thread-safe bitmap implementation using byte arrays
+// Package bitmap provides bitmap implementations, a dense word array Bitmap, a compressed RoaringBitmap and a lock-free AtomicBitmap
 package bitmap
 
 import (
 	"math/bits"
+	"slices"
 	"sync"
 )
 
-// Bitmap represents a thread-safe bitmap using a byte array
+const wordSize = 64
+
+// Bitmap represents a thread-safe bitmap using a uint64 word array
 type Bitmap struct {
-	mutex sync.Mutex
-	bits  []byte
-	size  int64 // Track original size for bounds checking
+	mutex    sync.Mutex
+	words    []uint64
+	size     int64 // Track original size for bounds checking
+	growable bool
+}
+
+// Option define the type of the bitmap option function
+type Option func(*Bitmap)
+
+// WithGrowable lets writes beyond Size() extend the bitmap instead of panicking,
+// and in-place set operations extend it to the size of the other operand
+// Reads and clears beyond Size() see unset bits, negative indexes still panic
+func WithGrowable() Option {
+	return func(b *Bitmap) {
+		b.growable = true
+	}
 }
 
 // NewBitmap creates a new Bitmap with the given size (in bits)
-func NewBitmap(size int64) *Bitmap {
+func NewBitmap(size int64, opts ...Option) *Bitmap {
 	if size < 0 {
 		panic("bitmap size must be non-negative")
 	}
 
-	return &Bitmap{
-		bits: make([]byte, (size+7)/8),
-		size: size,
+	b := &Bitmap{
+		words: make([]uint64, wordCount(size)),
+		size:  size,
+	}
+
+	for _, opt := range opts {
+		opt(b)
 	}
+
+	return b
 }
 
 // Set sets the bit at the given index to 1
 func (b *Bitmap) Set(index int64) {
-	b.validateIndex(index)
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
-	b.bits[index/8] |= 1 << (index % 8)
+	b.ensure(index)
+	b.words[index/wordSize] |= 1 << (index % wordSize)
 }
 
 // MSet sets multiple bits at the given indexes to 1
@@ -39,33 +61,34 @@ func (b *Bitmap) MSet(indexes []int64) {
 		return
 	}
 
-	for _, index := range indexes {
-		b.validateIndex(index)
-	}
-
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
+	// validate every index before writing so that a panic leaves the bitmap untouched
+	b.ensure(slices.Min(indexes))
+	b.ensure(slices.Max(indexes))
+
 	for _, index := range indexes {
-		b.bits[index/8] |= 1 << (index % 8)
+		b.words[index/wordSize] |= 1 << (index % wordSize)
 	}
 }
 
 // Clear clears the bit at the given index to 0
 func (b *Bitmap) Clear(index int64) {
-	b.validateIndex(index)
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
-	b.bits[index/8] &^= 1 << (index % 8)
+
+	if b.inRange(index) {
+		b.words[index/wordSize] &^= 1 << (index % wordSize)
+	}
 }
 
 // IsSet checks if the bit at the given index is set to 1
 func (b *Bitmap) IsSet(index int64) bool {
-	b.validateIndex(index)
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
-	return b.bits[index/8]&(1<<(index%8)) != 0
+	return b.inRange(index) && b.words[index/wordSize]&(1<<(index%wordSize)) != 0
 }
 
 // Count returns the number of bits set to 1 using efficient bit counting
@@ -73,23 +96,245 @@ func (b *Bitmap) Count() int64 {
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
+	return popcount(b.words)
+}
+
+// Size returns the capacity of the bitmap in bits
+func (b *Bitmap) Size() int64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.size
+}
+
+// Clone returns a deep copy of the bitmap
+func (b *Bitmap) Clone() *Bitmap {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	words := make([]uint64, len(b.words))
+	copy(words, b.words)
+
+	return &Bitmap{
+		words:    words,
+		size:     b.size,
+		growable: b.growable,
+	}
+}
+
+// And keeps only the bits that are also set in other
+func (b *Bitmap) And(other *Bitmap) {
+	b.apply(other, func(x, y uint64) uint64 { return x & y })
+}
+
+// Or sets the bits that are set in other
+// Bits of other beyond the size of b are ignored, unless b is growable and grows to the size of other
+func (b *Bitmap) Or(other *Bitmap) {
+	b.apply(other, func(x, y uint64) uint64 { return x | y })
+}
+
+// Xor flips the bits that are set in other
+// Bits of other beyond the size of b are ignored, unless b is growable and grows to the size of other
+func (b *Bitmap) Xor(other *Bitmap) {
+	b.apply(other, func(x, y uint64) uint64 { return x ^ y })
+}
+
+// AndNot clears the bits that are set in other
+func (b *Bitmap) AndNot(other *Bitmap) {
+	b.apply(other, func(x, y uint64) uint64 { return x &^ y })
+}
+
+// AndCount returns the number of bits set in both b and other without allocating a result
+func (b *Bitmap) AndCount(other *Bitmap) int64 {
+	return b.count(other, func(x, y uint64) uint64 { return x & y })
+}
+
+// OrCount returns the number of bits set in b or other without allocating a result
+func (b *Bitmap) OrCount(other *Bitmap) int64 {
+	return b.count(other, func(x, y uint64) uint64 { return x | y })
+}
+
+// And returns a new bitmap holding the intersection of a and b
+// The result has the size of the larger operand
+func And(a, b *Bitmap) *Bitmap {
+	return combine(a, b, func(x, y uint64) uint64 { return x & y })
+}
+
+// Or returns a new bitmap holding the union of a and b
+// The result has the size of the larger operand
+func Or(a, b *Bitmap) *Bitmap {
+	return combine(a, b, func(x, y uint64) uint64 { return x | y })
+}
+
+// Xor returns a new bitmap holding the symmetric difference of a and b
+// The result has the size of the larger operand
+func Xor(a, b *Bitmap) *Bitmap {
+	return combine(a, b, func(x, y uint64) uint64 { return x ^ y })
+}
+
+// AndNot returns a new bitmap holding the bits of a that are not set in b
+// The result has the size of the larger operand
+func AndNot(a, b *Bitmap) *Bitmap {
+	return combine(a, b, func(x, y uint64) uint64 { return x &^ y })
+}
+
+// apply combines every word of b with the matching word of other, missing words of other count as zero
+func (b *Bitmap) apply(other *Bitmap, op func(x, y uint64) uint64) {
+	words, size := other.snapshot()
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.growable {
+		b.grow(size)
+	}
+
+	for i := range b.words {
+		var y uint64
+		if i < len(words) {
+			y = words[i]
+		}
+
+		b.words[i] = op(b.words[i], y)
+	}
+
+	b.clearTail()
+}
+
+// countChunk is the number of words of the other operand count copies at a time
+const countChunk = 64
+
+// count returns the number of bits set in op of every word of b and the matching word of other,
+// missing words count as zero. The words of other are copied chunk by chunk into a stack buffer
+// and counted under the lock of b alone, so it neither allocates nor holds both locks
+func (b *Bitmap) count(other *Bitmap, op func(x, y uint64) uint64) int64 {
+	var buf [countChunk]uint64
+
 	count := int64(0)
 
-	for _, byteVal := range b.bits {
-		count += int64(bits.OnesCount8(byteVal))
+	for off := 0; ; off += countChunk {
+		n, more := other.copyWords(buf[:], off)
+
+		b.mutex.Lock()
+
+		for i := range n {
+			var x uint64
+			if off+i < len(b.words) {
+				x = b.words[off+i]
+			}
+
+			count += int64(bits.OnesCount64(op(x, buf[i])))
+		}
+
+		if !more {
+			for i := off + n; i < len(b.words); i++ {
+				count += int64(bits.OnesCount64(op(b.words[i], 0)))
+			}
+		}
+
+		b.mutex.Unlock()
+
+		if !more {
+			return count
+		}
 	}
+}
 
-	return count
+// copyWords copies the words of b from off into buf under the lock of b
+// It returns the number of words copied and whether b has more words after them
+func (b *Bitmap) copyWords(buf []uint64, off int) (n int, more bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if off < len(b.words) {
+		n = copy(buf, b.words[off:])
+	}
+
+	return n, off+n < len(b.words)
 }
 
-// Size returns the capacity of the bitmap in bits
-func (b *Bitmap) Size() int64 {
-	return b.size
+// combine builds a new bitmap from a and b word by word, missing words count as zero
+func combine(a, b *Bitmap, op func(x, y uint64) uint64) *Bitmap {
+	aWords, aSize := a.snapshot()
+	bWords, bSize := b.snapshot()
+
+	ret := NewBitmap(max(aSize, bSize))
+
+	for i := range ret.words {
+		var x, y uint64
+		if i < len(aWords) {
+			x = aWords[i]
+		}
+
+		if i < len(bWords) {
+			y = bWords[i]
+		}
+
+		ret.words[i] = op(x, y)
+	}
+
+	return ret
 }
 
-// validateIndex checks if index is within valid range
-func (b *Bitmap) validateIndex(index int64) {
-	if index < 0 || index >= b.size {
+// clearTail zeroes the bits of the last word that lie beyond size
+func (b *Bitmap) clearTail() {
+	if rem := b.size % wordSize; rem != 0 {
+		b.words[len(b.words)-1] &= 1<<rem - 1
+	}
+}
+
+// snapshot returns a copy of the words and the size, taken under the lock of b
+// Binary operations copy the other operand first and then lock the receiver alone, so concurrent
+// a.And(b) and b.And(a) never hold both locks
+func (b *Bitmap) snapshot() ([]uint64, int64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return slices.Clone(b.words), b.size
+}
+
+// ensure makes index writable, growing the bitmap if it is growable
+func (b *Bitmap) ensure(index int64) {
+	if b.inRange(index) {
+		return
+	}
+
+	b.grow(index + 1)
+}
+
+// inRange reports whether index is below the size
+// It panics on negative indexes, and on indexes beyond the size unless the bitmap is growable
+func (b *Bitmap) inRange(index int64) bool {
+	if index < 0 || (index >= b.size && !b.growable) {
 		panic("bitmap index out of range")
 	}
+
+	return index < b.size
+}
+
+// grow extends the bitmap to size bits, the word array grows with amortized appends
+func (b *Bitmap) grow(size int64) {
+	if size <= b.size {
+		return
+	}
+
+	if n := wordCount(size) - int64(len(b.words)); n > 0 {
+		b.words = append(b.words, make([]uint64, n)...)
+	}
+
+	b.size = size
+}
+
+func wordCount(size int64) int64 {
+	return (size + wordSize - 1) / wordSize
+}
+
+func popcount(words []uint64) int64 {
+	count := int64(0)
+
+	for _, w := range words {
+		count += int64(bits.OnesCount64(w))
+	}
+
+	return count
 }
@@ -222,6 +222,182 @@ func TestConcurrency(t *testing.T) {
 	}
 }
 
+func TestGrowable(t *testing.T) {
+	t.Parallel()
+
+	bm := NewBitmap(10, WithGrowable())
+
+	bm.Set(5)
+	assert.Equal(t, int64(10), bm.Size())
+
+	bm.Set(100)
+	assert.Equal(t, int64(101), bm.Size())
+	assert.True(t, bm.IsSet(100))
+	assert.False(t, bm.IsSet(99))
+	assert.False(t, bm.IsSet(1_000_000), "reads beyond size see unset bits")
+	assert.Equal(t, int64(101), bm.Size(), "reads must not grow")
+
+	bm.Clear(1_000_000)
+	assert.Equal(t, int64(101), bm.Size(), "clears must not grow")
+
+	bm.MSet([]int64{3, 500})
+	assert.Equal(t, int64(501), bm.Size())
+	assert.Equal(t, int64(4), bm.Count())
+
+	assert.Panics(t, func() { bm.Set(-1) }, "index out of range")
+	assert.Panics(t, func() { bm.IsSet(-1) }, "index out of range")
+
+	t.Run("mset validates before writing", func(t *testing.T) {
+		t.Parallel()
+
+		fixed := NewBitmap(10)
+		assert.Panics(t, func() { fixed.MSet([]int64{1, 10}) }, "index out of range")
+		assert.Equal(t, int64(0), fixed.Count())
+	})
+
+	t.Run("set algebra grows", func(t *testing.T) {
+		t.Parallel()
+
+		small := NewBitmap(10, WithGrowable())
+		large := NewBitmap(200)
+		large.Set(150)
+
+		small.Or(large)
+		assert.Equal(t, int64(200), small.Size())
+		assert.True(t, small.IsSet(150))
+
+		large.Set(199)
+		small.Xor(large)
+		assert.False(t, small.IsSet(150))
+		assert.True(t, small.IsSet(199))
+	})
+}
+
+func TestSetAlgebra(t *testing.T) {
+	t.Parallel()
+
+	newBitmap := func(size int64, indexes ...int64) *Bitmap {
+		bm := NewBitmap(size)
+		bm.MSet(indexes)
+
+		return bm
+	}
+
+	tests := []struct {
+		name    string
+		inPlace func(a, b *Bitmap)
+		fn      func(a, b *Bitmap) *Bitmap
+		want    []int64
+	}{
+		{"and", (*Bitmap).And, And, []int64{3, 64}},
+		{"or", (*Bitmap).Or, Or, []int64{1, 3, 64, 70, 99}},
+		{"xor", (*Bitmap).Xor, Xor, []int64{1, 70, 99}},
+		{"and not", (*Bitmap).AndNot, AndNot, []int64{1}},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := newBitmap(100, 1, 3, 64)
+			b := newBitmap(100, 3, 64, 70, 99)
+
+			ret := tt.fn(a, b)
+			assert.Equal(t, int64(100), ret.Size())
+			assert.Equal(t, int64(len(tt.want)), ret.Count())
+
+			for _, i := range tt.want {
+				assert.Truef(t, ret.IsSet(i), "Bit %d is not set", i)
+			}
+
+			assert.Equal(t, int64(3), a.Count(), "operands must be untouched")
+
+			tt.inPlace(a, b)
+			assert.Equal(t, ret.Count(), a.Count())
+			assert.Equal(t, ret.Count(), ret.AndCount(a))
+		})
+	}
+
+	t.Run("different sizes", func(t *testing.T) {
+		t.Parallel()
+
+		small := newBitmap(10, 1, 9)
+		large := newBitmap(130, 1, 9, 10, 129)
+
+		assert.Equal(t, int64(130), Or(small, large).Size())
+		assert.Equal(t, int64(2), And(small, large).Count())
+		assert.Equal(t, int64(2), Xor(large, small).Count())
+		assert.Equal(t, int64(2), small.AndCount(large))
+		assert.Equal(t, int64(4), small.OrCount(large))
+		assert.Equal(t, int64(4), large.OrCount(small))
+
+		small.Or(large)
+		assert.Equal(t, int64(2), small.Count(), "bits beyond size must be ignored")
+
+		small.Xor(newBitmap(64, 0, 10, 63))
+		assert.Equal(t, int64(3), small.Count())
+		assert.True(t, small.IsSet(0))
+		assert.Panics(t, func() { small.IsSet(10) }, "index out of range")
+	})
+
+	t.Run("counts across chunks", func(t *testing.T) {
+		t.Parallel()
+
+		small := NewBitmap(5000)
+		large := NewBitmap(20000)
+
+		for i := int64(0); i < large.Size(); i += 3 {
+			large.Set(i)
+
+			if i < small.Size() {
+				small.Set(i / 3 * 2)
+			}
+		}
+
+		and, or := And(small, large).Count(), Or(small, large).Count()
+		assert.Equal(t, and, small.AndCount(large))
+		assert.Equal(t, and, large.AndCount(small))
+		assert.Equal(t, or, small.OrCount(large))
+		assert.Equal(t, or, large.OrCount(small))
+		assert.Equal(t, large.Count(), large.AndCount(large))
+		assert.Equal(t, large.Count(), large.OrCount(large))
+	})
+
+	t.Run("self", func(t *testing.T) {
+		t.Parallel()
+
+		bm := newBitmap(10, 1, 2)
+		bm.Xor(bm)
+		assert.Equal(t, int64(0), bm.Count())
+	})
+}
+
+func TestSetAlgebraConcurrency(t *testing.T) {
+	t.Parallel()
+
+	a := NewBitmap(1024)
+	b := NewBitmap(1024)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			a.Or(b)
+		}()
+
+		go func() {
+			defer wg.Done()
+			b.And(a)
+		}()
+	}
+
+	wg.Wait()
+}
+
 // Benchmark tests
 func BenchmarkSet(b *testing.B) {
 	bm := NewBitmap(int64(b.N * 8))
@@ -272,3 +448,31 @@ func BenchmarkConcurrentAccess(b *testing.B) {
 		}
 	})
 }
+
+func BenchmarkAndCount(b *testing.B) {
+	x := NewBitmap(1024 * 1024)
+	y := NewBitmap(1024 * 1024)
+
+	for i := int64(0); i < x.Size(); i += 3 {
+		x.Set(i)
+		y.Set(i / 2)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		x.AndCount(y)
+	}
+
+	b.StopTimer()
+
+	// counting must not copy the operands, expect 0 B/op and 0 allocs/op with -benchmem
+	allocs := testing.AllocsPerRun(10, func() {
+		x.AndCount(y)
+		x.OrCount(y)
+	})
+	if allocs != 0 {
+		b.Fatalf("AndCount and OrCount allocate %v times per run", allocs)
+	}
+}
@@ -0,0 +1,207 @@
+package bitmap
+
+import (
+	"encoding"
+	"encoding/binary"
+	"hash/crc32"
+	"math/bits"
+
+	"github.com/go-pantheon/fabrica-util/errors"
+)
+
+// Binary layout of an encoded Bitmap, all header fields are big-endian:
+//
+//	magic    [2]byte "BM"
+//	version  uint8
+//	flags    uint8
+//	size     uint64 bitmap size in bits
+//	payload  [(size+7)/8]byte, bit i is stored in byte i/8 at position i%8 (LSB first)
+//	checksum uint32 CRC-32C of all preceding bytes, present when flagChecksum is set
+const (
+	encodingVersion    = 1
+	encodingHeaderSize = 12
+	checksumSize       = 4
+
+	flagChecksum = 1 << 0
+)
+
+var (
+	// ErrInvalidEncoding is returned when the encoded data is malformed
+	ErrInvalidEncoding = errors.New("bitmap: invalid encoding")
+	// ErrChecksumMismatch is returned when the checksum of the encoded data doesn't match
+	ErrChecksumMismatch = errors.New("bitmap: checksum mismatch")
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*Bitmap)(nil)
+	_ encoding.BinaryUnmarshaler = (*Bitmap)(nil)
+
+	encodingMagic = [2]byte{'B', 'M'}
+	crcTable      = crc32.MakeTable(crc32.Castagnoli)
+)
+
+type encodeOptions struct {
+	checksum bool
+}
+
+// EncodeOption define the type of the encoding option function
+type EncodeOption func(*encodeOptions)
+
+// WithChecksum appends a CRC-32C checksum that is verified on decoding
+func WithChecksum() EncodeOption {
+	return func(o *encodeOptions) {
+		o.checksum = true
+	}
+}
+
+// Encode serializes the bitmap into the versioned binary layout
+func (b *Bitmap) Encode(opts ...EncodeOption) []byte {
+	o := &encodeOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	payloadSize := byteCount(b.size)
+
+	data := make([]byte, encodingHeaderSize, encodingHeaderSize+payloadSize+checksumSize)
+	copy(data, encodingMagic[:])
+	data[2] = encodingVersion
+
+	if o.checksum {
+		data[3] |= flagChecksum
+	}
+
+	binary.BigEndian.PutUint64(data[4:], uint64(b.size))
+	data = append(data, wordsToBytes(b.words, payloadSize)...)
+
+	if o.checksum {
+		data = binary.BigEndian.AppendUint32(data, crc32.Checksum(data, crcTable))
+	}
+
+	return data
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, the checksum is always included
+func (b *Bitmap) MarshalBinary() ([]byte, error) {
+	return b.Encode(WithChecksum()), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing the size and content of the bitmap
+func (b *Bitmap) UnmarshalBinary(data []byte) error {
+	if len(data) < encodingHeaderSize || data[0] != encodingMagic[0] || data[1] != encodingMagic[1] {
+		return errors.Wrap(ErrInvalidEncoding, "bad header")
+	}
+
+	if data[2] != encodingVersion {
+		return errors.Wrapf(ErrInvalidEncoding, "unsupported version %d", data[2])
+	}
+
+	if data[3]&flagChecksum != 0 {
+		if len(data) < encodingHeaderSize+checksumSize {
+			return errors.Wrap(ErrInvalidEncoding, "missing checksum")
+		}
+
+		body, sum := data[:len(data)-checksumSize], data[len(data)-checksumSize:]
+		if crc32.Checksum(body, crcTable) != binary.BigEndian.Uint32(sum) {
+			return ErrChecksumMismatch
+		}
+
+		data = body
+	}
+
+	size := binary.BigEndian.Uint64(data[4:])
+	payloadSize := uint64(len(data) - encodingHeaderSize)
+
+	// compare against the payload first so that byteCount can't overflow on a forged size
+	if size > payloadSize*8 || uint64(byteCount(int64(size))) != payloadSize {
+		return errors.Wrapf(ErrInvalidEncoding, "payload of %d bytes doesn't match size %d", payloadSize, size)
+	}
+
+	words, err := bytesToWords(data[encodingHeaderSize:], int64(size))
+	if err != nil {
+		return err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.words = words
+	b.size = int64(size)
+
+	return nil
+}
+
+// RedisBytes returns the bitmap in the raw string layout used by Redis SETBIT/GETRANGE,
+// where bit i is stored in byte i/8 at position 7-i%8 (MSB first)
+func (b *Bitmap) RedisBytes() []byte {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	data := wordsToBytes(b.words, byteCount(b.size))
+	for i := range data {
+		data[i] = bits.Reverse8(data[i])
+	}
+
+	return data
+}
+
+// FromRedisBytes creates a Bitmap of the given size from the raw string of a Redis bitmap key
+// data may be shorter than the size since Redis only stores bytes up to the highest bit written
+func FromRedisBytes(data []byte, size int64) (*Bitmap, error) {
+	if size < 0 {
+		return nil, errors.Wrapf(ErrInvalidEncoding, "negative size %d", size)
+	}
+
+	if int64(len(data)) > byteCount(size) {
+		return nil, errors.Wrapf(ErrInvalidEncoding, "%d bytes exceed bitmap size %d", len(data), size)
+	}
+
+	buf := make([]byte, byteCount(size))
+	for i, v := range data {
+		buf[i] = bits.Reverse8(v)
+	}
+
+	words, err := bytesToWords(buf, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Bitmap{
+		words: words,
+		size:  size,
+	}, nil
+}
+
+// wordsToBytes flattens words into n little-endian bytes
+func wordsToBytes(words []uint64, n int64) []byte {
+	buf := make([]byte, len(words)*8)
+	for i, w := range words {
+		binary.LittleEndian.PutUint64(buf[i*8:], w)
+	}
+
+	return buf[:n]
+}
+
+// bytesToWords packs little-endian bytes into the words of a bitmap of the given size
+func bytesToWords(data []byte, size int64) ([]uint64, error) {
+	buf := make([]byte, wordCount(size)*8)
+	copy(buf, data)
+
+	words := make([]uint64, wordCount(size))
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint64(buf[i*8:])
+	}
+
+	if rem := size % wordSize; rem != 0 && words[len(words)-1]>>rem != 0 {
+		return nil, errors.Wrapf(ErrInvalidEncoding, "bits set beyond size %d", size)
+	}
+
+	return words, nil
+}
+
+func byteCount(size int64) int64 {
+	return (size + 7) / 8
+}
@@ -0,0 +1,143 @@
+package bitmap
+
+import (
+	"encoding/binary"
+	"slices"
+	"testing"
+
+	"github.com/go-pantheon/fabrica-util/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalBinary(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		size    int64
+		setBits []int64
+	}{
+		{"zero size", 0, nil},
+		{"partial byte", 5, []int64{0, 4}},
+		{"partial word", 70, []int64{1, 63, 64, 69}},
+		{"exact words", 128, []int64{0, 127}},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			bm := NewBitmap(tt.size)
+			bm.MSet(tt.setBits)
+
+			data, err := bm.MarshalBinary()
+			require.NoError(t, err)
+
+			got := &Bitmap{}
+			require.NoError(t, got.UnmarshalBinary(data))
+			assert.Equal(t, tt.size, got.Size())
+			assert.Equal(t, tt.setBits, slices.Collect(got.All()))
+
+			plain := bm.Encode()
+			assert.Len(t, plain, len(data)-checksumSize)
+			require.NoError(t, got.UnmarshalBinary(plain))
+			assert.Equal(t, tt.setBits, slices.Collect(got.All()))
+		})
+	}
+}
+
+func TestEncodingLayout(t *testing.T) {
+	t.Parallel()
+
+	bm := NewBitmap(12)
+	bm.MSet([]int64{0, 9})
+
+	want := []byte{'B', 'M', encodingVersion, 0, 0, 0, 0, 0, 0, 0, 0, 12, 0x01, 0x02}
+	assert.Equal(t, want, bm.Encode())
+}
+
+func TestUnmarshalBinaryErrors(t *testing.T) {
+	t.Parallel()
+
+	bm := NewBitmap(100)
+	bm.MSet([]int64{3, 50, 99})
+
+	valid, err := bm.MarshalBinary()
+	require.NoError(t, err)
+
+	corrupt := func(fn func(data []byte) []byte) []byte {
+		return fn(slices.Clone(valid))
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"empty", nil, ErrInvalidEncoding},
+		{"bad magic", corrupt(func(d []byte) []byte { d[0] = 'X'; return d }), ErrInvalidEncoding},
+		{"bad version", corrupt(func(d []byte) []byte { d[2] = 99; return d }), ErrInvalidEncoding},
+		{"flipped bit", corrupt(func(d []byte) []byte { d[encodingHeaderSize] ^= 1; return d }), ErrChecksumMismatch},
+		{"truncated", valid[:len(valid)-1], ErrChecksumMismatch},
+		{"forged size", func() []byte {
+			d := bm.Encode()
+			binary.BigEndian.PutUint64(d[4:], 1<<62)
+
+			return d
+		}(), ErrInvalidEncoding},
+		{"bits beyond size", func() []byte {
+			d := bm.Encode()
+			d[len(d)-1] = 0xff
+
+			return d
+		}(), ErrInvalidEncoding},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := NewBitmap(8)
+			err := got.UnmarshalBinary(tt.data)
+			assert.True(t, errors.Is(err, tt.wantErr), "unexpected error: %v", err)
+			assert.Equal(t, int64(8), got.Size(), "bitmap must be untouched on error")
+		})
+	}
+}
+
+func TestRedisBytes(t *testing.T) {
+	t.Parallel()
+
+	bm := NewBitmap(20)
+	bm.MSet([]int64{0, 9, 15, 16})
+
+	// SETBIT key 0 1, SETBIT key 9 1, SETBIT key 15 1, SETBIT key 16 1
+	want := []byte{0x80, 0x41, 0x80}
+	assert.Equal(t, want, bm.RedisBytes())
+
+	got, err := FromRedisBytes(want, 20)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{0, 9, 15, 16}, slices.Collect(got.All()))
+
+	t.Run("short data", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := FromRedisBytes([]byte{0x40}, 1000)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1000), got.Size())
+		assert.Equal(t, []int64{1}, slices.Collect(got.All()))
+	})
+
+	t.Run("data beyond size", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := FromRedisBytes([]byte{0, 0}, 8)
+		assert.True(t, errors.Is(err, ErrInvalidEncoding))
+
+		_, err = FromRedisBytes([]byte{0x01}, 7)
+		assert.True(t, errors.Is(err, ErrInvalidEncoding))
+	})
+}
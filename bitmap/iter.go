@@ -0,0 +1,146 @@
+package bitmap
+
+import (
+	"iter"
+	"math/bits"
+)
+
+// All returns an iterator over the indexes of the bits set to 1 in ascending order
+// Each word is read under the lock, so the loop body may modify the bitmap
+func (b *Bitmap) All() iter.Seq[int64] {
+	return func(yield func(int64) bool) {
+		for i := 0; ; i++ {
+			w, ok := b.word(i)
+			if !ok {
+				return
+			}
+
+			for w != 0 {
+				if !yield(int64(i)*wordSize + int64(bits.TrailingZeros64(w))) {
+					return
+				}
+
+				w &= w - 1
+			}
+		}
+	}
+}
+
+// NextSet returns the index of the first bit set to 1 at or after from
+func (b *Bitmap) NextSet(from int64) (int64, bool) {
+	return b.next(from, 0)
+}
+
+// NextClear returns the index of the first bit set to 0 at or after from
+func (b *Bitmap) NextClear(from int64) (int64, bool) {
+	return b.next(from, ^uint64(0))
+}
+
+// PrevSet returns the index of the last bit set to 1 at or before from
+func (b *Bitmap) PrevSet(from int64) (int64, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	from = min(from, b.size-1)
+	if from < 0 {
+		return 0, false
+	}
+
+	i := from / wordSize
+	w := b.words[i] << (wordSize - 1 - from%wordSize)
+
+	if w != 0 {
+		return from - int64(bits.LeadingZeros64(w)), true
+	}
+
+	for i--; i >= 0; i-- {
+		if b.words[i] != 0 {
+			return i*wordSize + wordSize - 1 - int64(bits.LeadingZeros64(b.words[i])), true
+		}
+	}
+
+	return 0, false
+}
+
+// Rank returns the number of bits set to 1 strictly below index
+func (b *Bitmap) Rank(index int64) int64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	index = min(max(index, 0), b.size)
+
+	count := popcount(b.words[:index/wordSize])
+	if rem := index % wordSize; rem != 0 {
+		count += int64(bits.OnesCount64(b.words[index/wordSize] & (1<<rem - 1)))
+	}
+
+	return count
+}
+
+// Select returns the index of the k-th bit set to 1, counting from 0
+// It is the inverse of Rank, Select(Rank(i)) == i for every set bit i
+func (b *Bitmap) Select(k int64) (int64, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if k < 0 {
+		return 0, false
+	}
+
+	for i, w := range b.words {
+		n := int64(bits.OnesCount64(w))
+		if k >= n {
+			k -= n
+			continue
+		}
+
+		for ; k > 0; k-- {
+			w &= w - 1
+		}
+
+		return int64(i)*wordSize + int64(bits.TrailingZeros64(w)), true
+	}
+
+	return 0, false
+}
+
+// next scans forward from from for the first bit that differs from the bits of skip
+func (b *Bitmap) next(from int64, skip uint64) (int64, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	from = max(from, 0)
+	if from >= b.size {
+		return 0, false
+	}
+
+	i := from / wordSize
+	w := (b.words[i] ^ skip) &^ (1<<(from%wordSize) - 1)
+
+	for w == 0 {
+		if i++; i >= int64(len(b.words)) {
+			return 0, false
+		}
+
+		w = b.words[i] ^ skip
+	}
+
+	index := i*wordSize + int64(bits.TrailingZeros64(w))
+	if index >= b.size {
+		return 0, false
+	}
+
+	return index, true
+}
+
+// word returns the i-th word under the lock
+func (b *Bitmap) word(i int) (uint64, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if i >= len(b.words) {
+		return 0, false
+	}
+
+	return b.words[i], true
+}
@@ -0,0 +1,146 @@
+package bitmap
+
+import (
+	mathrand "math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAll(t *testing.T) {
+	t.Parallel()
+
+	bm := NewBitmap(200)
+	want := []int64{0, 5, 63, 64, 127, 199}
+	bm.MSet(want)
+
+	assert.Equal(t, want, slices.Collect(bm.All()))
+
+	t.Run("break", func(t *testing.T) {
+		t.Parallel()
+
+		var got []int64
+
+		for i := range bm.All() {
+			if i > 63 {
+				break
+			}
+
+			got = append(got, i)
+		}
+
+		assert.Equal(t, []int64{0, 5, 63}, got)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Empty(t, slices.Collect(NewBitmap(0).All()))
+		assert.Empty(t, slices.Collect(NewBitmap(100).All()))
+	})
+}
+
+func TestNextPrev(t *testing.T) {
+	t.Parallel()
+
+	bm := NewBitmap(130)
+	bm.MSet([]int64{3, 64, 129})
+
+	tests := []struct {
+		name   string
+		fn     func(int64) (int64, bool)
+		from   int64
+		want   int64
+		wantOk bool
+	}{
+		{"next set from start", bm.NextSet, 0, 3, true},
+		{"next set on bit", bm.NextSet, 3, 3, true},
+		{"next set cross word", bm.NextSet, 4, 64, true},
+		{"next set last", bm.NextSet, 65, 129, true},
+		{"next set negative", bm.NextSet, -10, 3, true},
+		{"next set beyond", bm.NextSet, 130, 0, false},
+		{"next clear", bm.NextClear, 3, 4, true},
+		{"next clear on clear", bm.NextClear, 0, 0, true},
+		{"next clear at end", bm.NextClear, 129, 0, false},
+		{"prev set", bm.PrevSet, 128, 64, true},
+		{"prev set on bit", bm.PrevSet, 64, 64, true},
+		{"prev set beyond", bm.PrevSet, 1000, 129, true},
+		{"prev set none", bm.PrevSet, 2, 0, false},
+		{"prev set negative", bm.PrevSet, -1, 0, false},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := tt.fn(tt.from)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("next clear full", func(t *testing.T) {
+		t.Parallel()
+
+		full := NewBitmap(70)
+		for i := int64(0); i < full.Size(); i++ {
+			full.Set(i)
+		}
+
+		_, ok := full.NextClear(0)
+		assert.False(t, ok)
+	})
+}
+
+func TestRankSelect(t *testing.T) {
+	t.Parallel()
+
+	const size = 5000
+
+	r := mathrand.New(mathrand.NewPCG(3, 4))
+	bm := NewBitmap(size)
+
+	for i := 0; i < size/3; i++ {
+		bm.Set(r.Int64N(size))
+	}
+
+	rank := int64(0)
+
+	for i := int64(0); i < size; i++ {
+		assert.Equal(t, rank, bm.Rank(i))
+
+		if bm.IsSet(i) {
+			got, ok := bm.Select(rank)
+			assert.True(t, ok)
+			assert.Equal(t, i, got)
+
+			rank++
+		}
+	}
+
+	assert.Equal(t, bm.Count(), bm.Rank(size))
+	assert.Equal(t, bm.Count(), bm.Rank(size*2))
+	assert.Equal(t, int64(0), bm.Rank(-1))
+
+	_, ok := bm.Select(bm.Count())
+	assert.False(t, ok)
+
+	_, ok = bm.Select(-1)
+	assert.False(t, ok)
+}
+
+func BenchmarkAll(b *testing.B) {
+	bm := NewBitmap(1024 * 1024)
+	for i := int64(0); i < bm.Size(); i += 7 {
+		bm.Set(i)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for range bm.All() {
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package bitmap
+
+import "math/bits"
+
+// SetRange sets the bits in [start, end) to 1
+func (b *Bitmap) SetRange(start, end int64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.ensureRange(start, end)
+	eachWord(start, end, func(i int64, mask uint64) {
+		b.words[i] |= mask
+	})
+}
+
+// ClearRange clears the bits in [start, end) to 0
+func (b *Bitmap) ClearRange(start, end int64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	end = b.clampRange(start, end)
+	eachWord(start, end, func(i int64, mask uint64) {
+		b.words[i] &^= mask
+	})
+}
+
+// FlipRange inverts the bits in [start, end)
+func (b *Bitmap) FlipRange(start, end int64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.ensureRange(start, end)
+	eachWord(start, end, func(i int64, mask uint64) {
+		b.words[i] ^= mask
+	})
+}
+
+// CountRange returns the number of bits set to 1 in [start, end)
+func (b *Bitmap) CountRange(start, end int64) int64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	count := int64(0)
+
+	end = b.clampRange(start, end)
+	eachWord(start, end, func(i int64, mask uint64) {
+		count += int64(bits.OnesCount64(b.words[i] & mask))
+	})
+
+	return count
+}
+
+// ensureRange makes [start, end) writable, growing the bitmap if it is growable
+func (b *Bitmap) ensureRange(start, end int64) {
+	if b.clampRange(start, end) < end {
+		b.grow(end)
+	}
+}
+
+// clampRange validates [start, end) and returns end cut down to the size
+// It panics on invalid ranges, and on ranges beyond the size unless the bitmap is growable
+func (b *Bitmap) clampRange(start, end int64) int64 {
+	if start < 0 || start > end || (end > b.size && !b.growable) {
+		panic("bitmap range out of range")
+	}
+
+	return min(end, b.size)
+}
+
+// eachWord calls fn with the index of every word overlapping [start, end) and the mask of the range bits in it
+func eachWord(start, end int64, fn func(i int64, mask uint64)) {
+	if start >= end {
+		return
+	}
+
+	first, last := start/wordSize, (end-1)/wordSize
+
+	for i := first; i <= last; i++ {
+		mask := ^uint64(0)
+
+		if i == first {
+			mask &^= 1<<(start%wordSize) - 1
+		}
+
+		if rem := end % wordSize; i == last && rem != 0 {
+			mask &= 1<<rem - 1
+		}
+
+		fn(i, mask)
+	}
+}
@@ -0,0 +1,106 @@
+package bitmap
+
+import (
+	mathrand "math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRangeOperations(t *testing.T) {
+	t.Parallel()
+
+	const size = 300
+
+	r := mathrand.New(mathrand.NewPCG(5, 6))
+	bm := NewBitmap(size)
+	want := make([]bool, size)
+
+	for i := 0; i < 2000; i++ {
+		start := r.Int64N(size + 1)
+		end := start + r.Int64N(size-start+1)
+
+		switch r.IntN(4) {
+		case 0:
+			bm.SetRange(start, end)
+
+			for j := start; j < end; j++ {
+				want[j] = true
+			}
+		case 1:
+			bm.ClearRange(start, end)
+
+			for j := start; j < end; j++ {
+				want[j] = false
+			}
+		case 2:
+			bm.FlipRange(start, end)
+
+			for j := start; j < end; j++ {
+				want[j] = !want[j]
+			}
+		default:
+			count := int64(0)
+
+			for j := start; j < end; j++ {
+				if want[j] {
+					count++
+				}
+			}
+
+			assert.Equalf(t, count, bm.CountRange(start, end), "CountRange(%d, %d)", start, end)
+		}
+	}
+
+	for i := int64(0); i < size; i++ {
+		assert.Equalf(t, want[i], bm.IsSet(i), "Bit %d", i)
+	}
+}
+
+func TestRangeBounds(t *testing.T) {
+	t.Parallel()
+
+	bm := NewBitmap(100)
+	bm.SetRange(0, 100)
+	assert.Equal(t, int64(100), bm.Count())
+
+	bm.FlipRange(0, 100)
+	assert.Equal(t, int64(0), bm.Count())
+
+	bm.SetRange(10, 10)
+	assert.Equal(t, int64(0), bm.Count())
+
+	assert.Panics(t, func() { bm.SetRange(-1, 10) }, "range out of range")
+	assert.Panics(t, func() { bm.SetRange(10, 5) }, "range out of range")
+	assert.Panics(t, func() { bm.ClearRange(0, 101) }, "range out of range")
+	assert.Panics(t, func() { bm.CountRange(0, 101) }, "range out of range")
+}
+
+func TestGrowableRange(t *testing.T) {
+	t.Parallel()
+
+	bm := NewBitmap(0, WithGrowable())
+
+	// a season of 90 daily flags starting on day 30
+	bm.SetRange(30, 120)
+	assert.Equal(t, int64(120), bm.Size())
+	assert.Equal(t, int64(90), bm.CountRange(0, 1000))
+
+	bm.ClearRange(100, 1000)
+	assert.Equal(t, int64(120), bm.Size())
+	assert.Equal(t, int64(70), bm.Count())
+
+	bm.FlipRange(0, 200)
+	assert.Equal(t, int64(200), bm.Size())
+	assert.Equal(t, int64(130), bm.Count())
+}
+
+func BenchmarkSetRange(b *testing.B) {
+	bm := NewBitmap(1024 * 1024)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		bm.SetRange(17, bm.Size()-17)
+	}
+}
@@ -0,0 +1,196 @@
+// Package redisbitmap provides a Redis-backed bitmap shared by every process using the same key
+package redisbitmap
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/go-pantheon/fabrica-util/bitmap"
+	"github.com/go-pantheon/fabrica-util/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// MaxSize is the largest bitmap Redis can hold, a string value is limited to 512MB
+const MaxSize = 1 << 32
+
+// ErrIndexOutOfRange is returned when an index is negative or not below the bitmap size
+var ErrIndexOutOfRange = errors.New("bitmap index out of range")
+
+// Bitmap represents a bitmap stored in a Redis string key
+// It accepts the redis.UniversalClient returned by data/redis.NewStandalone or data/redis.NewCluster
+type Bitmap struct {
+	client redis.UniversalClient
+	key    string
+	size   int64
+}
+
+// New creates a new Bitmap with the given size (in bits) stored under key
+func New(client redis.UniversalClient, key string, size int64) *Bitmap {
+	if size < 0 || size > MaxSize {
+		panic("bitmap size must be between 0 and MaxSize")
+	}
+
+	return &Bitmap{
+		client: client,
+		key:    key,
+		size:   size,
+	}
+}
+
+// Set sets the bit at the given index to 1 with SETBIT
+func (b *Bitmap) Set(ctx context.Context, index int64) error {
+	if err := b.validateIndex(index); err != nil {
+		return err
+	}
+
+	if err := b.client.SetBit(ctx, b.key, index, 1).Err(); err != nil {
+		return errors.Wrapf(err, "redis setbit failed. key=%s index=%d", b.key, index)
+	}
+
+	return nil
+}
+
+// MSet sets multiple bits at the given indexes to 1, the SETBIT commands are sent in one pipeline
+func (b *Bitmap) MSet(ctx context.Context, indexes []int64) error {
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	for _, index := range indexes {
+		if err := b.validateIndex(index); err != nil {
+			return err
+		}
+	}
+
+	_, err := b.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, index := range indexes {
+			pipe.SetBit(ctx, b.key, index, 1)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "redis pipelined setbit failed. key=%s", b.key)
+	}
+
+	return nil
+}
+
+// Clear clears the bit at the given index to 0 with SETBIT
+func (b *Bitmap) Clear(ctx context.Context, index int64) error {
+	if err := b.validateIndex(index); err != nil {
+		return err
+	}
+
+	if err := b.client.SetBit(ctx, b.key, index, 0).Err(); err != nil {
+		return errors.Wrapf(err, "redis setbit failed. key=%s index=%d", b.key, index)
+	}
+
+	return nil
+}
+
+// IsSet checks if the bit at the given index is set to 1 with GETBIT
+func (b *Bitmap) IsSet(ctx context.Context, index int64) (bool, error) {
+	if err := b.validateIndex(index); err != nil {
+		return false, err
+	}
+
+	v, err := b.client.GetBit(ctx, b.key, index).Result()
+	if err != nil {
+		return false, errors.Wrapf(err, "redis getbit failed. key=%s index=%d", b.key, index)
+	}
+
+	return v == 1, nil
+}
+
+// MIsSet checks multiple bits in a single BITFIELD command
+func (b *Bitmap) MIsSet(ctx context.Context, indexes []int64) ([]bool, error) {
+	if len(indexes) == 0 {
+		return []bool{}, nil
+	}
+
+	args := make([]any, 0, len(indexes)*3)
+
+	for _, index := range indexes {
+		if err := b.validateIndex(index); err != nil {
+			return nil, err
+		}
+
+		args = append(args, "GET", "u1", strconv.FormatInt(index, 10))
+	}
+
+	vals, err := b.client.BitField(ctx, b.key, args...).Result()
+	if err != nil {
+		return nil, errors.Wrapf(err, "redis bitfield failed. key=%s", b.key)
+	}
+
+	if len(vals) != len(indexes) {
+		return nil, errors.Errorf("redis bitfield returned %d values for %d indexes. key=%s", len(vals), len(indexes), b.key)
+	}
+
+	ret := make([]bool, len(vals))
+	for i, v := range vals {
+		ret[i] = v == 1
+	}
+
+	return ret, nil
+}
+
+// Count returns the number of bits set to 1 with BITCOUNT
+func (b *Bitmap) Count(ctx context.Context) (int64, error) {
+	count, err := b.client.BitCount(ctx, b.key, nil).Result()
+	if err != nil {
+		return 0, errors.Wrapf(err, "redis bitcount failed. key=%s", b.key)
+	}
+
+	return count, nil
+}
+
+// Size returns the capacity of the bitmap in bits
+func (b *Bitmap) Size() int64 {
+	return b.size
+}
+
+// Key returns the Redis key holding the bitmap
+func (b *Bitmap) Key() string {
+	return b.key
+}
+
+// Snapshot pulls the whole key into a local bitmap.Bitmap of the same size
+// A missing key gives an empty bitmap
+func (b *Bitmap) Snapshot(ctx context.Context) (*bitmap.Bitmap, error) {
+	data, err := b.client.Get(ctx, b.key).Bytes()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, errors.Wrapf(err, "redis get failed. key=%s", b.key)
+	}
+
+	bm, err := bitmap.FromRedisBytes(data, b.size)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "redis key=%s", b.key)
+	}
+
+	return bm, nil
+}
+
+// Push overwrites the key with the content of a local bitmap in a single SET, keeping the key TTL
+// The local bitmap must not be larger than the remote one
+func (b *Bitmap) Push(ctx context.Context, bm *bitmap.Bitmap) error {
+	if bm.Size() > b.size {
+		return errors.Errorf("local bitmap size %d exceeds remote size %d. key=%s", bm.Size(), b.size, b.key)
+	}
+
+	if err := b.client.Set(ctx, b.key, bm.RedisBytes(), redis.KeepTTL).Err(); err != nil {
+		return errors.Wrapf(err, "redis set failed. key=%s", b.key)
+	}
+
+	return nil
+}
+
+// validateIndex checks if index is within valid range
+func (b *Bitmap) validateIndex(index int64) error {
+	if index < 0 || index >= b.size {
+		return errors.Wrapf(ErrIndexOutOfRange, "key=%s index=%d size=%d", b.key, index, b.size)
+	}
+
+	return nil
+}
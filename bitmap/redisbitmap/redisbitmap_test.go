@@ -0,0 +1,169 @@
+package redisbitmap
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/go-pantheon/fabrica-util/bitmap"
+	"github.com/go-pantheon/fabrica-util/errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBitmap(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	mr, client := newClient(t)
+	bm := New(client, "sign-in:2025", 366)
+
+	require.NoError(t, bm.Set(ctx, 0))
+	require.NoError(t, bm.MSet(ctx, []int64{9, 100, 365}))
+
+	for _, i := range []int64{0, 9, 100, 365} {
+		ok, err := bm.IsSet(ctx, i)
+		require.NoError(t, err)
+		assert.Truef(t, ok, "Bit %d is not set", i)
+	}
+
+	ok, err := bm.IsSet(ctx, 1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	count, err := bm.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+
+	got, err := bm.MIsSet(ctx, []int64{0, 1, 9, 365})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, false, true, true}, got)
+
+	require.NoError(t, bm.Clear(ctx, 9))
+
+	count, err = bm.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	// the key must use the Redis SETBIT layout, bit 0 is the MSB of the first byte
+	raw, err := mr.Get(bm.Key())
+	require.NoError(t, err)
+	assert.Equal(t, byte(0x80), raw[0])
+}
+
+func TestBitmapOutOfRange(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	_, client := newClient(t)
+	bm := New(client, "out-of-range", 10)
+
+	assert.True(t, errors.Is(bm.Set(ctx, 10), ErrIndexOutOfRange))
+	assert.True(t, errors.Is(bm.Clear(ctx, -1), ErrIndexOutOfRange))
+	assert.True(t, errors.Is(bm.MSet(ctx, []int64{1, 10}), ErrIndexOutOfRange))
+
+	_, err := bm.IsSet(ctx, 10)
+	assert.True(t, errors.Is(err, ErrIndexOutOfRange))
+
+	_, err = bm.MIsSet(ctx, []int64{10})
+	assert.True(t, errors.Is(err, ErrIndexOutOfRange))
+
+	count, err := bm.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count, "invalid batches must not write")
+
+	assert.Panics(t, func() { New(client, "too-large", MaxSize+1) })
+}
+
+func TestSnapshotAndPush(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	mr, client := newClient(t)
+	remote := New(client, "achievements", 1000)
+
+	t.Run("missing key", func(t *testing.T) {
+		t.Parallel()
+
+		local, err := New(client, "missing", 100).Snapshot(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(100), local.Size())
+		assert.Equal(t, int64(0), local.Count())
+	})
+
+	require.NoError(t, remote.MSet(ctx, []int64{3, 64, 999}))
+
+	local, err := remote.Snapshot(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), local.Size())
+	assert.Equal(t, int64(3), local.Count())
+	assert.True(t, local.IsSet(64))
+
+	local.Clear(3)
+	local.SetRange(500, 510)
+
+	mr.SetTTL(remote.Key(), time.Hour)
+	require.NoError(t, remote.Push(ctx, local))
+	assert.Equal(t, time.Hour, mr.TTL(remote.Key()), "push must keep the key TTL")
+
+	count, err := remote.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), count)
+
+	ok, err := remote.IsSet(ctx, 505)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	assert.Error(t, remote.Push(ctx, bitmap.NewBitmap(1001)))
+}
+
+// newClient starts a miniredis server standing in for Redis, with the BITFIELD GET u1 subset MIsSet relies on
+func newClient(t *testing.T) (*miniredis.Miniredis, redis.UniversalClient) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	require.NoError(t, mr.Server().Register("BITFIELD", bitfieldGet(mr)))
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return mr, client
+}
+
+func bitfieldGet(mr *miniredis.Miniredis) server.Cmd {
+	return func(c *server.Peer, _ string, args []string) {
+		if len(args) == 0 || (len(args)-1)%3 != 0 {
+			c.WriteError("ERR wrong number of arguments for 'bitfield' command")
+			return
+		}
+
+		value, _ := mr.Get(args[0])
+		ret := make([]int, 0, len(args)/3)
+
+		for i := 1; i < len(args); i += 3 {
+			offset, err := strconv.Atoi(args[i+2])
+			if !strings.EqualFold(args[i], "GET") || args[i+1] != "u1" || err != nil {
+				c.WriteError("ERR only GET u1 <offset> is supported")
+				return
+			}
+
+			bit := 0
+			if offset/8 < len(value) && value[offset/8]&(0x80>>(offset%8)) != 0 {
+				bit = 1
+			}
+
+			ret = append(ret, bit)
+		}
+
+		c.WriteLen(len(ret))
+
+		for _, v := range ret {
+			c.WriteInt(v)
+		}
+	}
+}
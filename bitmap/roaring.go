@@ -0,0 +1,488 @@
+package bitmap
+
+import (
+	"math/bits"
+	"slices"
+	"sync"
+)
+
+const (
+	// arrayMaxSize is the max cardinality of an array container, beyond it a bitmap container is smaller
+	arrayMaxSize = 4096
+	// bitmapWords is the number of uint64 words needed to hold a 64K chunk
+	bitmapWords = (1 << 16) / 64
+	// bitmapSizeInBytes is the fixed memory cost of a bitmap container
+	bitmapSizeInBytes = bitmapWords * 8
+)
+
+// RoaringBitmap represents a thread-safe compressed bitmap for sparse or dense int64 indexes.
+// Indexes are split into 64K chunks by their high 48 bits, and each chunk is kept in
+// whichever of an array, bitmap or run container is the most compact for its content.
+type RoaringBitmap struct {
+	mutex      sync.Mutex
+	keys       []uint64 // sorted high 48 bits of the chunks
+	containers []container
+}
+
+// NewRoaringBitmap creates a new empty RoaringBitmap
+func NewRoaringBitmap() *RoaringBitmap {
+	return &RoaringBitmap{}
+}
+
+// Set sets the bit at the given index to 1
+func (rb *RoaringBitmap) Set(index int64) {
+	key, low := splitIndex(index)
+
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+
+	i := rb.getOrCreate(key)
+	rb.containers[i] = rb.containers[i].add(low)
+}
+
+// MSet sets multiple bits at the given indexes to 1
+// Touched chunks are re-evaluated afterwards so that a batch of consecutive indexes ends up in a run container
+func (rb *RoaringBitmap) MSet(indexes []int64) {
+	if len(indexes) == 0 {
+		return
+	}
+
+	sorted := slices.Clone(indexes)
+	slices.Sort(sorted)
+
+	if sorted[0] < 0 {
+		panic("bitmap index out of range")
+	}
+
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+
+	for start := 0; start < len(sorted); {
+		key, _ := splitIndex(sorted[start])
+		i := rb.getOrCreate(key)
+
+		end := start
+		for ; end < len(sorted); end++ {
+			k, low := splitIndex(sorted[end])
+			if k != key {
+				break
+			}
+
+			rb.containers[i] = rb.containers[i].add(low)
+		}
+
+		rb.containers[i] = optimize(rb.containers[i])
+		start = end
+	}
+}
+
+// Clear clears the bit at the given index to 0
+func (rb *RoaringBitmap) Clear(index int64) {
+	key, low := splitIndex(index)
+
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+
+	i, ok := slices.BinarySearch(rb.keys, key)
+	if !ok {
+		return
+	}
+
+	c := rb.containers[i].remove(low)
+	if c.cardinality() == 0 {
+		rb.keys = slices.Delete(rb.keys, i, i+1)
+		rb.containers = slices.Delete(rb.containers, i, i+1)
+
+		return
+	}
+
+	rb.containers[i] = c
+}
+
+// IsSet checks if the bit at the given index is set to 1
+func (rb *RoaringBitmap) IsSet(index int64) bool {
+	key, low := splitIndex(index)
+
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+
+	i, ok := slices.BinarySearch(rb.keys, key)
+	if !ok {
+		return false
+	}
+
+	return rb.containers[i].contains(low)
+}
+
+// Count returns the number of bits set to 1
+func (rb *RoaringBitmap) Count() int64 {
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+
+	count := int64(0)
+
+	for _, c := range rb.containers {
+		count += int64(c.cardinality())
+	}
+
+	return count
+}
+
+// RunOptimize converts every chunk to its most compact container, including run containers
+// Set only switches between array and bitmap containers, call it after bulk single-bit updates
+func (rb *RoaringBitmap) RunOptimize() {
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+
+	for i, c := range rb.containers {
+		rb.containers[i] = optimize(c)
+	}
+}
+
+// SizeInBytes returns the approximate memory used by the containers
+func (rb *RoaringBitmap) SizeInBytes() int64 {
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+
+	size := int64(len(rb.keys) * 8)
+
+	for _, c := range rb.containers {
+		size += int64(c.sizeInBytes())
+	}
+
+	return size
+}
+
+// getOrCreate returns the position of the container for key, inserting an empty array container if absent
+func (rb *RoaringBitmap) getOrCreate(key uint64) int {
+	i, ok := slices.BinarySearch(rb.keys, key)
+	if !ok {
+		rb.keys = slices.Insert(rb.keys, i, key)
+		rb.containers = slices.Insert(rb.containers, i, container(&arrayContainer{}))
+	}
+
+	return i
+}
+
+// splitIndex splits index into the chunk key and the offset inside the chunk
+func splitIndex(index int64) (uint64, uint16) {
+	if index < 0 {
+		panic("bitmap index out of range")
+	}
+
+	return uint64(index) >> 16, uint16(index)
+}
+
+// container stores the low 16 bits of the indexes within one 64K chunk
+// add and remove return the container that should replace the receiver
+type container interface {
+	add(x uint16) container
+	remove(x uint16) container
+	contains(x uint16) bool
+	cardinality() int
+	numRuns() int
+	sizeInBytes() int
+	each(fn func(x uint16))
+}
+
+// optimize returns the most compact representation of c
+func optimize(c container) container {
+	card := c.cardinality()
+	runSize := runSizeInBytes(c.numRuns())
+	arraySize := arraySizeInBytes(card)
+
+	switch {
+	case runSize < min(arraySize, bitmapSizeInBytes):
+		if _, ok := c.(*runContainer); ok {
+			return c
+		}
+
+		return toRun(c)
+	case card <= arrayMaxSize:
+		if _, ok := c.(*arrayContainer); ok {
+			return c
+		}
+
+		return toArray(c)
+	default:
+		if _, ok := c.(*bitmapContainer); ok {
+			return c
+		}
+
+		return toBitmap(c)
+	}
+}
+
+func arraySizeInBytes(card int) int {
+	return card * 2
+}
+
+func runSizeInBytes(runs int) int {
+	return 2 + runs*4
+}
+
+func toArray(c container) *arrayContainer {
+	ac := &arrayContainer{content: make([]uint16, 0, c.cardinality())}
+	c.each(func(x uint16) {
+		ac.content = append(ac.content, x)
+	})
+
+	return ac
+}
+
+func toBitmap(c container) *bitmapContainer {
+	bc := &bitmapContainer{}
+	c.each(func(x uint16) {
+		bc.words[x/64] |= 1 << (x % 64)
+		bc.card++
+	})
+
+	return bc
+}
+
+func toRun(c container) *runContainer {
+	rc := &runContainer{runs: make([]interval16, 0, c.numRuns())}
+	c.each(func(x uint16) {
+		if n := len(rc.runs); n > 0 && int(rc.runs[n-1].last())+1 == int(x) {
+			rc.runs[n-1].length++
+			return
+		}
+
+		rc.runs = append(rc.runs, interval16{start: x})
+	})
+
+	return rc
+}
+
+// arrayContainer keeps a sorted slice of the set offsets, used for sparse chunks
+type arrayContainer struct {
+	content []uint16
+}
+
+func (ac *arrayContainer) add(x uint16) container {
+	i, ok := slices.BinarySearch(ac.content, x)
+	if ok {
+		return ac
+	}
+
+	if len(ac.content) >= arrayMaxSize {
+		return toBitmap(ac).add(x)
+	}
+
+	ac.content = slices.Insert(ac.content, i, x)
+
+	return ac
+}
+
+func (ac *arrayContainer) remove(x uint16) container {
+	if i, ok := slices.BinarySearch(ac.content, x); ok {
+		ac.content = slices.Delete(ac.content, i, i+1)
+	}
+
+	return ac
+}
+
+func (ac *arrayContainer) contains(x uint16) bool {
+	_, ok := slices.BinarySearch(ac.content, x)
+	return ok
+}
+
+func (ac *arrayContainer) cardinality() int {
+	return len(ac.content)
+}
+
+func (ac *arrayContainer) numRuns() int {
+	runs := 0
+
+	for i, x := range ac.content {
+		if i == 0 || ac.content[i-1]+1 != x {
+			runs++
+		}
+	}
+
+	return runs
+}
+
+func (ac *arrayContainer) sizeInBytes() int {
+	return arraySizeInBytes(len(ac.content))
+}
+
+func (ac *arrayContainer) each(fn func(x uint16)) {
+	for _, x := range ac.content {
+		fn(x)
+	}
+}
+
+// bitmapContainer keeps a dense 64K bit array, used for chunks with more than arrayMaxSize offsets
+type bitmapContainer struct {
+	words [bitmapWords]uint64
+	card  int
+}
+
+func (bc *bitmapContainer) add(x uint16) container {
+	mask := uint64(1) << (x % 64)
+	if bc.words[x/64]&mask == 0 {
+		bc.words[x/64] |= mask
+		bc.card++
+	}
+
+	return bc
+}
+
+func (bc *bitmapContainer) remove(x uint16) container {
+	mask := uint64(1) << (x % 64)
+	if bc.words[x/64]&mask == 0 {
+		return bc
+	}
+
+	bc.words[x/64] &^= mask
+	bc.card--
+
+	if bc.card <= arrayMaxSize {
+		return toArray(bc)
+	}
+
+	return bc
+}
+
+func (bc *bitmapContainer) contains(x uint16) bool {
+	return bc.words[x/64]&(1<<(x%64)) != 0
+}
+
+func (bc *bitmapContainer) cardinality() int {
+	return bc.card
+}
+
+// numRuns counts the run starts, a set bit whose lower neighbour is clear
+func (bc *bitmapContainer) numRuns() int {
+	runs := 0
+	carry := uint64(0)
+
+	for _, w := range bc.words {
+		runs += bits.OnesCount64(w &^ (w<<1 | carry))
+		carry = w >> 63
+	}
+
+	return runs
+}
+
+func (bc *bitmapContainer) sizeInBytes() int {
+	return bitmapSizeInBytes
+}
+
+func (bc *bitmapContainer) each(fn func(x uint16)) {
+	for i, w := range bc.words {
+		for w != 0 {
+			fn(uint16(i*64 + bits.TrailingZeros64(w)))
+			w &= w - 1
+		}
+	}
+}
+
+// interval16 is a run of consecutive offsets [start, start+length]
+type interval16 struct {
+	start  uint16
+	length uint16
+}
+
+func (iv interval16) last() uint16 {
+	return iv.start + iv.length
+}
+
+// runContainer keeps sorted non-adjacent runs, used for chunks made of long consecutive ranges
+type runContainer struct {
+	runs []interval16
+}
+
+// search returns the position of the last run starting at or before x, or -1
+func (rc *runContainer) search(x uint16) int {
+	i, _ := slices.BinarySearchFunc(rc.runs, x, func(iv interval16, x uint16) int {
+		return int(iv.start) - int(x)
+	})
+
+	if i < len(rc.runs) && rc.runs[i].start == x {
+		return i
+	}
+
+	return i - 1
+}
+
+func (rc *runContainer) add(x uint16) container {
+	i := rc.search(x)
+	if i >= 0 && x <= rc.runs[i].last() {
+		return rc
+	}
+
+	joinPrev := i >= 0 && int(rc.runs[i].last())+1 == int(x)
+	joinNext := i+1 < len(rc.runs) && int(x)+1 == int(rc.runs[i+1].start)
+
+	switch {
+	case joinPrev && joinNext:
+		rc.runs[i].length += rc.runs[i+1].length + 2
+		rc.runs = slices.Delete(rc.runs, i+1, i+2)
+	case joinPrev:
+		rc.runs[i].length++
+	case joinNext:
+		rc.runs[i+1].start--
+		rc.runs[i+1].length++
+	default:
+		rc.runs = slices.Insert(rc.runs, i+1, interval16{start: x})
+	}
+
+	return optimize(rc)
+}
+
+func (rc *runContainer) remove(x uint16) container {
+	i := rc.search(x)
+	if i < 0 || x > rc.runs[i].last() {
+		return rc
+	}
+
+	iv := rc.runs[i]
+
+	switch {
+	case iv.length == 0:
+		rc.runs = slices.Delete(rc.runs, i, i+1)
+	case x == iv.start:
+		rc.runs[i].start++
+		rc.runs[i].length--
+	case x == iv.last():
+		rc.runs[i].length--
+	default:
+		rc.runs[i].length = x - iv.start - 1
+		rc.runs = slices.Insert(rc.runs, i+1, interval16{start: x + 1, length: iv.last() - x - 1})
+	}
+
+	return optimize(rc)
+}
+
+func (rc *runContainer) contains(x uint16) bool {
+	i := rc.search(x)
+	return i >= 0 && x <= rc.runs[i].last()
+}
+
+func (rc *runContainer) cardinality() int {
+	card := 0
+
+	for _, iv := range rc.runs {
+		card += int(iv.length) + 1
+	}
+
+	return card
+}
+
+func (rc *runContainer) numRuns() int {
+	return len(rc.runs)
+}
+
+func (rc *runContainer) sizeInBytes() int {
+	return runSizeInBytes(len(rc.runs))
+}
+
+func (rc *runContainer) each(fn func(x uint16)) {
+	for _, iv := range rc.runs {
+		for x := int(iv.start); x <= int(iv.last()); x++ {
+			fn(uint16(x))
+		}
+	}
+}
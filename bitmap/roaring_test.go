@@ -0,0 +1,204 @@
+package bitmap
+
+import (
+	"math"
+	mathrand "math/rand/v2"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoaringBitmap(t *testing.T) {
+	t.Parallel()
+
+	rb := NewRoaringBitmap()
+	indexes := []int64{0, 1, 65535, 65536, 1 << 32, math.MaxInt64}
+
+	for _, i := range indexes {
+		rb.Set(i)
+	}
+
+	for _, i := range indexes {
+		assert.Truef(t, rb.IsSet(i), "Bit %d is not set", i)
+	}
+
+	assert.False(t, rb.IsSet(2))
+	assert.False(t, rb.IsSet(math.MaxInt64-1))
+	assert.Equal(t, int64(len(indexes)), rb.Count())
+
+	for _, i := range indexes {
+		rb.Clear(i)
+	}
+
+	assert.Equal(t, int64(0), rb.Count())
+	assert.Equal(t, int64(0), rb.SizeInBytes())
+
+	assert.Panics(t, func() { rb.Set(-1) }, "index out of range")
+	assert.Panics(t, func() { rb.MSet([]int64{1, -1}) }, "index out of range")
+}
+
+func TestRoaringContainers(t *testing.T) {
+	t.Parallel()
+
+	t.Run("array to bitmap and back", func(t *testing.T) {
+		t.Parallel()
+
+		rb := NewRoaringBitmap()
+		for i := int64(0); i <= arrayMaxSize; i++ {
+			rb.Set(i * 2)
+		}
+
+		assert.IsType(t, &bitmapContainer{}, rb.containers[0])
+		assert.Equal(t, int64(arrayMaxSize+1), rb.Count())
+
+		rb.Clear(0)
+		assert.IsType(t, &arrayContainer{}, rb.containers[0])
+		assert.Equal(t, int64(arrayMaxSize), rb.Count())
+	})
+
+	t.Run("dense range becomes run", func(t *testing.T) {
+		t.Parallel()
+
+		rb := NewRoaringBitmap()
+		for i := int64(0); i < 1<<16; i++ {
+			rb.Set(i)
+		}
+
+		assert.IsType(t, &bitmapContainer{}, rb.containers[0])
+
+		rb.RunOptimize()
+		assert.IsType(t, &runContainer{}, rb.containers[0])
+		assert.Equal(t, int64(1<<16), rb.Count())
+		assert.Less(t, rb.SizeInBytes(), int64(16))
+
+		rb.Clear(100)
+		assert.False(t, rb.IsSet(100))
+		assert.True(t, rb.IsSet(99))
+		assert.True(t, rb.IsSet(101))
+		assert.Equal(t, int64(1<<16-1), rb.Count())
+
+		rb.Set(100)
+		assert.Len(t, rb.containers[0].(*runContainer).runs, 1)
+	})
+
+	t.Run("mset optimizes", func(t *testing.T) {
+		t.Parallel()
+
+		indexes := make([]int64, 0, 10_000)
+		for i := int64(0); i < 10_000; i++ {
+			indexes = append(indexes, 1<<40+i)
+		}
+
+		rb := NewRoaringBitmap()
+		rb.MSet(indexes)
+
+		assert.IsType(t, &runContainer{}, rb.containers[0])
+		assert.Equal(t, int64(len(indexes)), rb.Count())
+	})
+
+	t.Run("fragmented run falls back", func(t *testing.T) {
+		t.Parallel()
+
+		rb := NewRoaringBitmap()
+		rb.MSet([]int64{0, 1, 2, 3})
+		assert.IsType(t, &runContainer{}, rb.containers[0])
+
+		for i := int64(10); i < 20_000; i += 2 {
+			rb.Set(i)
+		}
+
+		assert.IsType(t, &bitmapContainer{}, rb.containers[0])
+		assert.Equal(t, int64(4+(20_000-10)/2), rb.Count())
+	})
+}
+
+func TestRoaringMatchesBitmap(t *testing.T) {
+	t.Parallel()
+
+	const size = 1 << 18
+
+	r := mathrand.New(mathrand.NewPCG(1, 2))
+	rb := NewRoaringBitmap()
+	bm := NewBitmap(size)
+
+	for i := 0; i < 200_000; i++ {
+		index := r.Int64N(size)
+		if r.IntN(3) == 0 {
+			rb.Clear(index)
+			bm.Clear(index)
+		} else {
+			rb.Set(index)
+			bm.Set(index)
+		}
+
+		if i%50_000 == 0 {
+			rb.RunOptimize()
+		}
+	}
+
+	assert.Equal(t, bm.Count(), rb.Count())
+
+	for i := int64(0); i < size; i++ {
+		if bm.IsSet(i) != rb.IsSet(i) {
+			t.Fatalf("IsSet(%d) mismatch", i)
+		}
+	}
+}
+
+func TestRoaringSparseMemory(t *testing.T) {
+	t.Parallel()
+
+	rb := NewRoaringBitmap()
+	for i := int64(0); i < 1000; i++ {
+		rb.Set(i * 1_000_000_007)
+	}
+
+	assert.Equal(t, int64(1000), rb.Count())
+	assert.Less(t, rb.SizeInBytes(), int64(1000*16))
+}
+
+func TestRoaringConcurrency(t *testing.T) {
+	t.Parallel()
+
+	rb := NewRoaringBitmap()
+
+	var wg sync.WaitGroup
+
+	for i := int64(0); i < 1000; i++ {
+		wg.Add(1)
+
+		go func(idx int64) {
+			defer wg.Done()
+			rb.Set(idx << 20)
+			rb.IsSet(idx << 20)
+		}(i)
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, int64(1000), rb.Count())
+}
+
+func BenchmarkRoaringSet(b *testing.B) {
+	rb := NewRoaringBitmap()
+
+	b.ResetTimer()
+
+	for i := int64(0); i < int64(b.N); i++ {
+		rb.Set(i * 97)
+	}
+}
+
+func BenchmarkRoaringIsSet(b *testing.B) {
+	rb := NewRoaringBitmap()
+	for i := int64(0); i < 1<<20; i++ {
+		rb.Set(i * 97)
+	}
+
+	b.ResetTimer()
+
+	for i := int64(0); i < int64(b.N); i++ {
+		rb.IsSet(i * 97)
+	}
+}
@@ -0,0 +1,94 @@
+package bloom
+
+import (
+	"math"
+
+	"github.com/go-pantheon/fabrica-util/bitmap"
+)
+
+const (
+	// blockBits is the size of a block, one 64-byte CPU cache line
+	blockBits = 512
+)
+
+// BlockedInt64BloomFilter is a cache-line blocked Bloom filter for int64
+// The bit array is partitioned into 512-bit blocks and all k bits of an element live in the same
+// block, so an Add or Contains touches a single cache line instead of k random ones. The price is
+// a slightly higher false positive rate than a classic filter of the same size.
+type BlockedInt64BloomFilter struct {
+	bitmap   *bitmap.Bitmap
+	hashFunc []func(int64) int64
+	blocks   int64
+	k        int64
+}
+
+// NewBlockedInt64Bloom create a cache-line blocked int64 Bloom filter
+// n: expected element count
+// p: expected false positive rate (0 < p < 1)
+func NewBlockedInt64Bloom(n int64, p float64) *BlockedInt64BloomFilter {
+	m, k := estimateParameters(n, p)
+	blocks := (m + blockBits - 1) / blockBits
+
+	return &BlockedInt64BloomFilter{
+		bitmap:   bitmap.NewBitmap(blocks * blockBits),
+		hashFunc: createInt64HashFunctions(2, 0),
+		blocks:   blocks,
+		k:        k,
+	}
+}
+
+// Add add int64 element
+func (bf *BlockedInt64BloomFilter) Add(data int64) {
+	bf.bitmap.MSet(bf.locations(data))
+}
+
+// MAdd add multiple int64 elements
+func (bf *BlockedInt64BloomFilter) MAdd(data []int64) {
+	if len(data) == 0 {
+		return
+	}
+
+	indexes := make([]int64, 0, int64(len(data))*bf.k)
+	for _, d := range data {
+		indexes = append(indexes, bf.locations(d)...)
+	}
+
+	bf.bitmap.MSet(indexes)
+}
+
+// Contains check if the element may exist
+func (bf *BlockedInt64BloomFilter) Contains(data int64) bool {
+	for _, i := range bf.locations(data) {
+		if !bf.bitmap.IsSet(i) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FillRatio returns the fraction of bits set to 1
+func (bf *BlockedInt64BloomFilter) FillRatio() float64 {
+	return float64(bf.bitmap.Count()) / float64(bf.bitmap.Size())
+}
+
+// FalsePositiveRate returns the current false positive probability estimated from the fill ratio
+// Blocks fill unevenly, so the real rate is slightly above this estimate
+func (bf *BlockedInt64BloomFilter) FalsePositiveRate() float64 {
+	return math.Pow(bf.FillRatio(), float64(bf.k))
+}
+
+// locations returns the k bit indexes of data, the first hash picks the block and the second one
+// drives double hashing inside it. An odd step keeps the k positions distinct within the block.
+func (bf *BlockedInt64BloomFilter) locations(data int64) []int64 {
+	base := (bf.hashFunc[0](data) % bf.blocks) * blockBits
+	h := bf.hashFunc[1](data)
+	start, step := h%blockBits, (h/blockBits)|1
+
+	indexes := make([]int64, bf.k)
+	for i := range indexes {
+		indexes[i] = base + (start+int64(i)*step)%blockBits
+	}
+
+	return indexes
+}
@@ -0,0 +1,89 @@
+package bloom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockedInt64BloomFilter(t *testing.T) {
+	t.Parallel()
+
+	const (
+		n = 10_000
+		p = 0.01
+	)
+
+	bf := NewBlockedInt64Bloom(n, p)
+	assert.Equal(t, int64(0), bf.bitmap.Size()%blockBits)
+
+	data := make([]int64, 0, n)
+	for i := int64(0); i < n; i++ {
+		data = append(data, i*7919)
+	}
+
+	bf.MAdd(data[:n/2])
+
+	for _, d := range data[n/2:] {
+		bf.Add(d)
+	}
+
+	for _, d := range data {
+		assert.Truef(t, bf.Contains(d), "Should contain added element %d", d)
+	}
+
+	falsePositives := 0
+	total := 100_000
+
+	r := newRand()
+
+	for i := 0; i < total; i++ {
+		if bf.Contains(-1 - r.Int64N(1<<40)) {
+			falsePositives++
+		}
+	}
+
+	fpRate := float64(falsePositives) / float64(total)
+	assert.Less(t, fpRate, 2*p, "False positive rate too high: %f", fpRate)
+	assert.InDelta(t, 0.5, bf.FillRatio(), 0.1)
+	assert.InDelta(t, p, bf.FalsePositiveRate(), p)
+}
+
+func TestBlockedLocations(t *testing.T) {
+	t.Parallel()
+
+	bf := NewBlockedInt64Bloom(1000, 0.001)
+
+	for d := int64(-100); d < 100; d++ {
+		locations := bf.locations(d)
+		assert.Len(t, locations, int(bf.k))
+
+		block := locations[0] / blockBits
+		seen := make(map[int64]bool, len(locations))
+
+		for _, l := range locations {
+			assert.Equal(t, block, l/blockBits, "all bits must be in one block")
+			assert.False(t, seen[l], "bits must be distinct")
+
+			seen[l] = true
+		}
+	}
+}
+
+func BenchmarkBlockedInt64Bloom(b *testing.B) {
+	bf := NewBlockedInt64Bloom(1000000, 0.01)
+
+	r := newRand()
+	data := make([]int64, b.N)
+
+	for i := range data {
+		data[i] = r.Int64()
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		bf.Add(data[i])
+		bf.Contains(data[i])
+	}
+}
@@ -5,30 +5,48 @@ import (
 	"math"
 
 	"github.com/go-pantheon/fabrica-util/bitmap"
+	"github.com/go-pantheon/fabrica-util/errors"
 )
 
+// ErrIncompatible is returned when merging filters built with different parameters
+var ErrIncompatible = errors.New("bloom: incompatible filter parameters")
+
 // Int64BloomFilter optimized Bloom filter for int64
 type Int64BloomFilter struct {
 	bitmap   *bitmap.Bitmap
 	hashFunc []func(int64) int64
 	size     int64
+	seed     int64
+}
+
+// Option define the type of the int64 Bloom filter option function
+type Option func(*Int64BloomFilter)
+
+// WithSeed set the hash seed, filters only merge with filters using the same seed
+func WithSeed(seed int64) Option {
+	return func(bf *Int64BloomFilter) {
+		bf.seed = seed
+	}
 }
 
 // NewInt64Bloom create int64 optimized Bloom filter
 // n: expected element count
 // p: expected false positive rate (0 < p < 1)
-func NewInt64Bloom(n int64, p float64) *Int64BloomFilter {
-	m, k := estimateParameters(n, p)
-	// limit max hash function count to 8
-	if k > 8 {
-		k = 8
+func NewInt64Bloom(n int64, p float64, opts ...Option) *Int64BloomFilter {
+	m, k := Int64Parameters(n, p)
+
+	bf := &Int64BloomFilter{
+		bitmap: bitmap.NewBitmap(m),
+		size:   m,
 	}
 
-	return &Int64BloomFilter{
-		bitmap:   bitmap.NewBitmap(m),
-		hashFunc: createInt64HashFunctions(k),
-		size:     m,
+	for _, opt := range opts {
+		opt(bf)
 	}
+
+	bf.hashFunc = createInt64HashFunctions(k, bf.seed)
+
+	return bf
 }
 
 // Add add int64 element
@@ -69,6 +87,87 @@ func (bf *Int64BloomFilter) Contains(data int64) bool {
 	return true
 }
 
+// FillRatio returns the fraction of bits set to 1
+func (bf *Int64BloomFilter) FillRatio() float64 {
+	return float64(bf.bitmap.Count()) / float64(bf.size)
+}
+
+// FalsePositiveRate returns the current false positive probability estimated from the fill ratio
+func (bf *Int64BloomFilter) FalsePositiveRate() float64 {
+	return math.Pow(bf.FillRatio(), float64(len(bf.hashFunc)))
+}
+
+// Union merges other into bf, bf then contains every element added to either filter
+// Both filters must share the same size, hash function count and seed
+func (bf *Int64BloomFilter) Union(other *Int64BloomFilter) error {
+	if err := bf.checkCompatible(other); err != nil {
+		return err
+	}
+
+	bf.bitmap.Or(other.bitmap)
+
+	return nil
+}
+
+// Intersect keeps in bf only the bits also set in other
+// Elements added to both filters are still contained, the false positive rate is at most
+// the one of either filter but may be higher than a filter built from the intersection
+func (bf *Int64BloomFilter) Intersect(other *Int64BloomFilter) error {
+	if err := bf.checkCompatible(other); err != nil {
+		return err
+	}
+
+	bf.bitmap.And(other.bitmap)
+
+	return nil
+}
+
+func (bf *Int64BloomFilter) checkCompatible(other *Int64BloomFilter) error {
+	if bf.size != other.size || len(bf.hashFunc) != len(other.hashFunc) || bf.seed != other.seed {
+		return errors.Wrapf(ErrIncompatible, "m=%d/%d k=%d/%d seed=%d/%d",
+			bf.size, other.size, len(bf.hashFunc), len(other.hashFunc), bf.seed, other.seed)
+	}
+
+	return nil
+}
+
+// Int64Hasher computes the bit locations Int64BloomFilter uses for an element,
+// so that filters kept in other storages such as Redis share the same hashing
+type Int64Hasher struct {
+	hashFunc []func(int64) int64
+	size     int64
+}
+
+// NewInt64Hasher create the hasher of an int64 filter with m bits, k hash functions and the hash seed
+func NewInt64Hasher(m, k, seed int64) *Int64Hasher {
+	return &Int64Hasher{
+		hashFunc: createInt64HashFunctions(k, seed),
+		size:     m,
+	}
+}
+
+// Locations returns the k bit indexes of data, in the order Int64BloomFilter sets them
+func (h *Int64Hasher) Locations(data int64) []int64 {
+	indexes := make([]int64, len(h.hashFunc))
+	for i, fn := range h.hashFunc {
+		indexes[i] = fn(data) % h.size
+	}
+
+	return indexes
+}
+
+// Int64Parameters returns the bit array size m and hash function count k NewInt64Bloom uses
+// for n expected elements and false positive rate p
+func Int64Parameters(n int64, p float64) (m, k int64) {
+	m, k = estimateParameters(n, p)
+	// limit max hash function count to 8
+	if k > 8 {
+		k = 8
+	}
+
+	return m, k
+}
+
 // estimateParameters calculate optimal parameters (m: array size, k: hash function count)
 func estimateParameters(n int64, p float64) (int64, int64) {
 	m := int64(math.Ceil(-float64(n) * math.Log(p) / (math.Pow(math.Log(2), 2))))
@@ -78,7 +177,8 @@ func estimateParameters(n int64, p float64) (int64, int64) {
 }
 
 // create int64 optimized hash functions
-func createInt64HashFunctions(k int64) []func(int64) int64 {
+// the seed is mixed into the input, a zero seed gives the original functions
+func createInt64HashFunctions(k int64, seed int64) []func(int64) int64 {
 	return func() []func(int64) int64 {
 		fns := make([]func(int64) int64, k)
 
@@ -94,9 +194,9 @@ func createInt64HashFunctions(k int64) []func(int64) int64 {
 		}
 
 		for i := int64(0); i < k && i < int64(len(primes)); i++ {
-			seed := primes[i]
+			prime := primes[i]
 			fns[i] = func(data int64) int64 {
-				h := data * seed
+				h := (data ^ seed) * prime
 				h ^= h >> 33
 				h *= 0x5136ead0f35b37d // replace overflow constant
 				h ^= h >> 33
@@ -109,9 +209,9 @@ func createInt64HashFunctions(k int64) []func(int64) int64 {
 
 		// if k is greater than the number of predefined primes, use the variant
 		for i := int64(len(primes)); i < k; i++ {
-			seed := primes[i%int64(len(primes))] ^ (i * 0x1234567)
+			variant := primes[i%int64(len(primes))] ^ (i * 0x1234567)
 			fns[i] = func(data int64) int64 {
-				h := data ^ seed
+				h := data ^ seed ^ variant
 				h ^= h >> 33
 				h *= 0x5136ead0f35b37d // replace overflow constant
 				h ^= h >> 33
@@ -6,7 +6,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-pantheon/fabrica-util/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInt64BloomFilter(t *testing.T) {
@@ -143,6 +145,92 @@ func TestBatchAdd(t *testing.T) {
 	})
 }
 
+func TestInt64BloomSeed(t *testing.T) {
+	t.Parallel()
+
+	unseeded := NewInt64Bloom(1000, 0.01)
+	zero := NewInt64Bloom(1000, 0.01, WithSeed(0))
+	seeded := NewInt64Bloom(1000, 0.01, WithSeed(7))
+
+	for i := int64(0); i < 100; i++ {
+		unseeded.Add(i)
+		zero.Add(i)
+		seeded.Add(i)
+	}
+
+	assert.Equal(t, int64(0), unseeded.bitmap.OrCount(zero.bitmap)-unseeded.bitmap.Count(), "zero seed must keep the original hashing")
+	assert.NotEqual(t, unseeded.bitmap.Count(), unseeded.bitmap.AndCount(seeded.bitmap))
+}
+
+func TestInt64Hasher(t *testing.T) {
+	t.Parallel()
+
+	m, k := Int64Parameters(1000, 1e-9)
+	assert.Equal(t, int64(8), k, "k must be capped at 8")
+
+	bf := NewInt64Bloom(1000, 1e-9, WithSeed(3))
+	assert.Equal(t, m, bf.size)
+
+	h := NewInt64Hasher(m, k, 3)
+	bf.Add(12345)
+
+	locations := h.Locations(12345)
+	assert.Len(t, locations, int(k))
+
+	for _, l := range locations {
+		assert.Truef(t, bf.bitmap.IsSet(l), "Location %d is not set", l)
+	}
+}
+
+func TestUnionIntersect(t *testing.T) {
+	t.Parallel()
+
+	newFilter := func(data ...int64) *Int64BloomFilter {
+		bf := NewInt64Bloom(1000, 0.01, WithSeed(1))
+		bf.MAdd(data)
+
+		return bf
+	}
+
+	t.Run("union", func(t *testing.T) {
+		t.Parallel()
+
+		a := newFilter(1, 2, 3)
+		b := newFilter(3, 4, 5)
+
+		require.NoError(t, a.Union(b))
+
+		for _, d := range []int64{1, 2, 3, 4, 5} {
+			assert.Truef(t, a.Contains(d), "Should contain %d after union", d)
+		}
+	})
+
+	t.Run("intersect", func(t *testing.T) {
+		t.Parallel()
+
+		a := newFilter(1, 2, 3)
+		b := newFilter(3, 4, 5)
+
+		require.NoError(t, a.Intersect(b))
+		assert.True(t, a.Contains(3))
+
+		for _, d := range []int64{1, 2, 4, 5} {
+			assert.Falsef(t, a.Contains(d), "Should not contain %d after intersect", d)
+		}
+	})
+
+	t.Run("incompatible", func(t *testing.T) {
+		t.Parallel()
+
+		a := newFilter(1)
+
+		assert.True(t, errors.Is(a.Union(NewInt64Bloom(1000, 0.01)), ErrIncompatible), "different seed")
+		assert.True(t, errors.Is(a.Union(NewInt64Bloom(2000, 0.01, WithSeed(1))), ErrIncompatible), "different size")
+		assert.True(t, errors.Is(a.Intersect(NewInt64Bloom(1000, 0.1, WithSeed(1))), ErrIncompatible), "different k")
+		assert.True(t, a.Contains(1), "filter must be untouched")
+	})
+}
+
 func BenchmarkAdd(b *testing.B) {
 	bf := NewInt64Bloom(100000, 0.01)
 
@@ -0,0 +1,120 @@
+package bloom
+
+import (
+	"sync"
+
+	"github.com/go-pantheon/fabrica-util/bitmap"
+)
+
+// maxCounter is the saturation value of a 4-bit counter
+const maxCounter = 0x0f
+
+// CountingInt64BloomFilter is a Bloom filter for int64 that supports removal
+// Every bit of a classic filter is backed by a 4-bit counter, and the bitmap keeps a bit set while
+// its counter is non-zero, so Contains costs the same as Int64BloomFilter.Contains. Counters
+// saturate at 15 and are never decremented again, which keeps removals from causing false negatives.
+type CountingInt64BloomFilter struct {
+	mutex    sync.Mutex
+	bitmap   *bitmap.Bitmap
+	counters []byte // two 4-bit counters per byte, the low nibble holds the even index
+	hashFunc []func(int64) int64
+	size     int64
+}
+
+// NewCountingInt64Bloom create a counting int64 Bloom filter
+// n: expected element count
+// p: expected false positive rate (0 < p < 1)
+func NewCountingInt64Bloom(n int64, p float64) *CountingInt64BloomFilter {
+	m, k := Int64Parameters(n, p)
+
+	return &CountingInt64BloomFilter{
+		bitmap:   bitmap.NewBitmap(m),
+		counters: make([]byte, (m+1)/2),
+		hashFunc: createInt64HashFunctions(k, 0),
+		size:     m,
+	}
+}
+
+// Add add int64 element
+func (bf *CountingInt64BloomFilter) Add(data int64) {
+	bf.mutex.Lock()
+	defer bf.mutex.Unlock()
+
+	bf.add(data)
+}
+
+// MAdd add multiple int64 elements
+func (bf *CountingInt64BloomFilter) MAdd(data []int64) {
+	if len(data) == 0 {
+		return
+	}
+
+	bf.mutex.Lock()
+	defer bf.mutex.Unlock()
+
+	for _, d := range data {
+		bf.add(d)
+	}
+}
+
+// Remove remove an element added before
+// It returns false and changes nothing when the element definitely doesn't exist
+// Removing an element that was never added may cause false negatives for other elements
+func (bf *CountingInt64BloomFilter) Remove(data int64) bool {
+	bf.mutex.Lock()
+	defer bf.mutex.Unlock()
+
+	if !bf.Contains(data) {
+		return false
+	}
+
+	for _, fn := range bf.hashFunc {
+		h := fn(data) % bf.size
+
+		c := bf.counter(h)
+		if c == maxCounter {
+			continue
+		}
+
+		bf.setCounter(h, c-1)
+
+		if c == 1 {
+			bf.bitmap.Clear(h)
+		}
+	}
+
+	return true
+}
+
+// Contains check if the element may exist
+func (bf *CountingInt64BloomFilter) Contains(data int64) bool {
+	for _, fn := range bf.hashFunc {
+		h := fn(data) % bf.size
+		if !bf.bitmap.IsSet(h) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (bf *CountingInt64BloomFilter) add(data int64) {
+	for _, fn := range bf.hashFunc {
+		h := fn(data) % bf.size
+
+		if c := bf.counter(h); c < maxCounter {
+			bf.setCounter(h, c+1)
+		}
+
+		bf.bitmap.Set(h)
+	}
+}
+
+func (bf *CountingInt64BloomFilter) counter(index int64) byte {
+	return bf.counters[index/2] >> (index % 2 * 4) & maxCounter
+}
+
+func (bf *CountingInt64BloomFilter) setCounter(index int64, c byte) {
+	shift := index % 2 * 4
+	bf.counters[index/2] = bf.counters[index/2]&^(maxCounter<<shift) | c<<shift
+}
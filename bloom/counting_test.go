@@ -0,0 +1,93 @@
+package bloom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountingInt64BloomFilter(t *testing.T) {
+	t.Parallel()
+
+	const n = 1000
+
+	bf := NewCountingInt64Bloom(n, 0.01)
+
+	for i := int64(0); i < n; i++ {
+		bf.Add(i)
+	}
+
+	for i := int64(0); i < n; i += 2 {
+		assert.Truef(t, bf.Remove(i), "Should remove added element %d", i)
+	}
+
+	for i := int64(1); i < n; i += 2 {
+		assert.Truef(t, bf.Contains(i), "Removal must not cause false negatives, element %d", i)
+	}
+
+	falsePositives := 0
+
+	for i := int64(0); i < n; i += 2 {
+		if bf.Contains(i) {
+			falsePositives++
+		}
+	}
+
+	assert.Less(t, falsePositives, n/2/50, "Removed elements should be gone")
+
+	for i := int64(1); i < n; i += 2 {
+		bf.Remove(i)
+	}
+
+	assert.Equal(t, int64(0), bf.bitmap.Count(), "All bits must be cleared once every element is removed")
+	assert.False(t, bf.Remove(1), "Removing a missing element must report false")
+}
+
+func TestCountingDuplicates(t *testing.T) {
+	t.Parallel()
+
+	bf := NewCountingInt64Bloom(100, 0.01)
+	bf.MAdd([]int64{7, 7, 7})
+	bf.MAdd(nil)
+
+	assert.True(t, bf.Remove(7))
+	assert.True(t, bf.Remove(7))
+	assert.True(t, bf.Contains(7))
+	assert.True(t, bf.Remove(7))
+	assert.False(t, bf.Contains(7))
+}
+
+func TestCountingSaturation(t *testing.T) {
+	t.Parallel()
+
+	bf := NewCountingInt64Bloom(100, 0.01)
+
+	for range 20 {
+		bf.Add(42)
+	}
+
+	h := bf.hashFunc[0](42) % bf.size
+	assert.Equal(t, byte(maxCounter), bf.counter(h))
+
+	for range 20 {
+		bf.Remove(42)
+	}
+
+	assert.True(t, bf.Contains(42), "Saturated counters must never reach zero")
+}
+
+func TestCounterPacking(t *testing.T) {
+	t.Parallel()
+
+	bf := NewCountingInt64Bloom(10, 0.01)
+
+	bf.setCounter(0, 3)
+	bf.setCounter(1, 12)
+	bf.setCounter(2, 15)
+
+	assert.Equal(t, byte(3), bf.counter(0))
+	assert.Equal(t, byte(12), bf.counter(1))
+	assert.Equal(t, byte(15), bf.counter(2))
+	assert.Equal(t, byte(0), bf.counter(3))
+	assert.Equal(t, byte(0xc3), bf.counters[0])
+}
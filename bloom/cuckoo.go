@@ -0,0 +1,260 @@
+package bloom
+
+import (
+	"math/bits"
+	mathrand "math/rand/v2"
+	"sync"
+
+	"github.com/go-pantheon/fabrica-util/internal/hashmix"
+)
+
+const (
+	// DefaultFingerprintBits is the default fingerprint size of a cuckoo filter
+	DefaultFingerprintBits = 16
+
+	cuckooBucketSize = 4
+	cuckooMaxKicks   = 500
+	cuckooMaxLoad    = 0.95
+)
+
+// Int64CuckooFilter is a cuckoo filter for int64 that supports deletion
+// Each element is stored as a short fingerprint in one of two candidate buckets, the false positive
+// rate is about 2*4/2^f for f fingerprint bits. Unlike a counting Bloom filter, deleting an element
+// that was never added can remove another element sharing its fingerprint, so only delete known elements.
+type Int64CuckooFilter struct {
+	mutex sync.RWMutex
+
+	buckets []uint64 // cuckooBucketSize slots per bucket of fpBits bits each, packed, 0 marks an empty slot
+	mask    uint64   // bucket count - 1, the bucket count is a power of two
+	fpBits  uint
+	fpMask  uint64
+	count   int64
+
+	// victim holds the fingerprint left homeless by a failed insertion, so that it is not lost
+	victim struct {
+		used  bool
+		index uint64
+		fp    uint16
+	}
+}
+
+// CuckooOption define the type of the cuckoo filter option function
+type CuckooOption func(*Int64CuckooFilter)
+
+// WithFingerprintBits set the fingerprint size in bits, between 4 and 16
+// More bits lower the false positive rate, the slots are packed so the memory grows with them
+func WithFingerprintBits(fpBits uint) CuckooOption {
+	return func(cf *Int64CuckooFilter) {
+		if fpBits >= 4 && fpBits <= 16 {
+			cf.fpBits = fpBits
+		}
+	}
+}
+
+// NewInt64Cuckoo create an int64 cuckoo filter able to hold at least capacity elements
+func NewInt64Cuckoo(capacity int64, opts ...CuckooOption) *Int64CuckooFilter {
+	numBuckets := uint64(1)
+	for float64(capacity) > float64(numBuckets*cuckooBucketSize)*cuckooMaxLoad {
+		numBuckets <<= 1
+	}
+
+	cf := &Int64CuckooFilter{
+		mask:   numBuckets - 1,
+		fpBits: DefaultFingerprintBits,
+	}
+
+	for _, opt := range opts {
+		opt(cf)
+	}
+
+	cf.fpMask = 1<<cf.fpBits - 1
+	cf.buckets = make([]uint64, (numBuckets*cuckooBucketSize*uint64(cf.fpBits)+63)/64)
+
+	return cf
+}
+
+// Add add int64 element, returns false when the filter is full
+// The same element may be added several times, each copy needs its own Delete
+func (cf *Int64CuckooFilter) Add(data int64) bool {
+	cf.mutex.Lock()
+	defer cf.mutex.Unlock()
+
+	if cf.victim.used {
+		return false
+	}
+
+	i1, i2, fp := cf.locate(data)
+	if cf.insert(i1, fp) || cf.insert(i2, fp) {
+		cf.count++
+		return true
+	}
+
+	// kick a random fingerprint out to its alternate bucket until every fingerprint has a slot
+	i := i1
+	if mathrand.IntN(2) == 0 {
+		i = i2
+	}
+
+	for range cuckooMaxKicks {
+		slot := i*cuckooBucketSize + uint64(mathrand.IntN(cuckooBucketSize))
+		kicked := cf.slot(slot)
+		cf.setSlot(slot, fp)
+		fp = kicked
+
+		i = cf.altIndex(i, fp)
+		if cf.insert(i, fp) {
+			cf.count++
+			return true
+		}
+	}
+
+	cf.victim.used = true
+	cf.victim.index = i
+	cf.victim.fp = fp
+	cf.count++
+
+	return true
+}
+
+// Contains check if the element may exist
+func (cf *Int64CuckooFilter) Contains(data int64) bool {
+	cf.mutex.RLock()
+	defer cf.mutex.RUnlock()
+
+	i1, i2, fp := cf.locate(data)
+
+	return cf.find(i1, fp) >= 0 || cf.find(i2, fp) >= 0 || cf.isVictim(i1, i2, fp)
+}
+
+// Delete remove one copy of an element added before, returns false if it wasn't found
+func (cf *Int64CuckooFilter) Delete(data int64) bool {
+	cf.mutex.Lock()
+	defer cf.mutex.Unlock()
+
+	i1, i2, fp := cf.locate(data)
+
+	switch {
+	case cf.isVictim(i1, i2, fp):
+		cf.victim.used = false
+	case cf.remove(i1, fp) || cf.remove(i2, fp):
+		// the victim can move into the slot just freed
+		if v := cf.victim; v.used && (cf.insert(v.index, v.fp) || cf.insert(cf.altIndex(v.index, v.fp), v.fp)) {
+			cf.victim.used = false
+		}
+	default:
+		return false
+	}
+
+	cf.count--
+
+	return true
+}
+
+// Count returns the number of elements stored
+func (cf *Int64CuckooFilter) Count() int64 {
+	cf.mutex.RLock()
+	defer cf.mutex.RUnlock()
+
+	return cf.count
+}
+
+// LoadFactor returns the fraction of occupied slots
+func (cf *Int64CuckooFilter) LoadFactor() float64 {
+	cf.mutex.RLock()
+	defer cf.mutex.RUnlock()
+
+	return float64(cf.count) / float64(cf.slots())
+}
+
+// FalsePositiveRate returns the upper bound of the false positive probability at the current load
+func (cf *Int64CuckooFilter) FalsePositiveRate() float64 {
+	cf.mutex.RLock()
+	defer cf.mutex.RUnlock()
+
+	load := float64(cf.count) / float64(cf.slots())
+
+	return min(1, 2*cuckooBucketSize*load/float64(cf.fpMask))
+}
+
+// locate returns both candidate buckets and the fingerprint of data
+func (cf *Int64CuckooFilter) locate(data int64) (uint64, uint64, uint16) {
+	h := hashmix.Mix64(uint64(data))
+
+	fp := uint16(bits.RotateLeft64(h, 32) & cf.fpMask)
+	if fp == 0 {
+		fp = 1
+	}
+
+	i1 := h & cf.mask
+
+	return i1, cf.altIndex(i1, fp), fp
+}
+
+// altIndex returns the other candidate bucket, i1 = altIndex(i2, fp) and i2 = altIndex(i1, fp)
+func (cf *Int64CuckooFilter) altIndex(i uint64, fp uint16) uint64 {
+	return (i ^ hashmix.Mix64(uint64(fp))) & cf.mask
+}
+
+func (cf *Int64CuckooFilter) insert(i uint64, fp uint16) bool {
+	if slot := cf.find(i, 0); slot >= 0 {
+		cf.setSlot(uint64(slot), fp)
+		return true
+	}
+
+	return false
+}
+
+func (cf *Int64CuckooFilter) remove(i uint64, fp uint16) bool {
+	if slot := cf.find(i, fp); slot >= 0 {
+		cf.setSlot(uint64(slot), 0)
+		return true
+	}
+
+	return false
+}
+
+// find returns the slot of bucket i holding fp, or -1
+func (cf *Int64CuckooFilter) find(i uint64, fp uint16) int {
+	start := int(i * cuckooBucketSize)
+
+	for slot := start; slot < start+cuckooBucketSize; slot++ {
+		if cf.slot(uint64(slot)) == fp {
+			return slot
+		}
+	}
+
+	return -1
+}
+
+// slots returns the number of fingerprint slots
+func (cf *Int64CuckooFilter) slots() uint64 {
+	return (cf.mask + 1) * cuckooBucketSize
+}
+
+// slot returns the fingerprint in slot, which may span two words
+func (cf *Int64CuckooFilter) slot(slot uint64) uint16 {
+	bit := slot * uint64(cf.fpBits)
+	word, offset := bit/64, bit%64
+
+	v := cf.buckets[word] >> offset
+	if offset+uint64(cf.fpBits) > 64 {
+		v |= cf.buckets[word+1] << (64 - offset)
+	}
+
+	return uint16(v & cf.fpMask)
+}
+
+// setSlot stores fp in slot, which may span two words
+func (cf *Int64CuckooFilter) setSlot(slot uint64, fp uint16) {
+	bit := slot * uint64(cf.fpBits)
+	word, offset := bit/64, bit%64
+
+	cf.buckets[word] = cf.buckets[word]&^(cf.fpMask<<offset) | uint64(fp)<<offset
+	if offset+uint64(cf.fpBits) > 64 {
+		cf.buckets[word+1] = cf.buckets[word+1]&^(cf.fpMask>>(64-offset)) | uint64(fp)>>(64-offset)
+	}
+}
+
+func (cf *Int64CuckooFilter) isVictim(i1, i2 uint64, fp uint16) bool {
+	return cf.victim.used && cf.victim.fp == fp && (cf.victim.index == i1 || cf.victim.index == i2)
+}
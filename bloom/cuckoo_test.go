@@ -0,0 +1,158 @@
+package bloom
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInt64CuckooFilter(t *testing.T) {
+	t.Parallel()
+
+	const n = 10_000
+
+	cf := NewInt64Cuckoo(n)
+
+	for i := int64(0); i < n; i++ {
+		assert.Truef(t, cf.Add(i*31), "Should add element %d", i)
+	}
+
+	assert.Equal(t, int64(n), cf.Count())
+	assert.Greater(t, cf.LoadFactor(), 0.5)
+
+	for i := int64(0); i < n; i++ {
+		assert.Truef(t, cf.Contains(i*31), "Should contain added element %d", i)
+	}
+
+	falsePositives := 0
+	total := 100_000
+
+	for i := int64(0); i < int64(total); i++ {
+		if cf.Contains(-1 - i) {
+			falsePositives++
+		}
+	}
+
+	fpRate := float64(falsePositives) / float64(total)
+	assert.Less(t, fpRate, 0.001, "False positive rate too high: %f", fpRate)
+	assert.Less(t, cf.FalsePositiveRate(), 0.001)
+
+	for i := int64(0); i < n; i += 2 {
+		assert.Truef(t, cf.Delete(i*31), "Should delete element %d", i)
+	}
+
+	for i := int64(1); i < n; i += 2 {
+		assert.Truef(t, cf.Contains(i*31), "Delete must not cause false negatives, element %d", i)
+	}
+
+	assert.Equal(t, int64(n/2), cf.Count())
+	assert.False(t, cf.Delete(0))
+}
+
+func TestCuckooFingerprintBits(t *testing.T) {
+	t.Parallel()
+
+	small := NewInt64Cuckoo(1000, WithFingerprintBits(4))
+	large := NewInt64Cuckoo(1000, WithFingerprintBits(16))
+	invalid := NewInt64Cuckoo(1000, WithFingerprintBits(20))
+
+	assert.Equal(t, uint64(0x0f), small.fpMask)
+	assert.Equal(t, uint64(1<<DefaultFingerprintBits-1), invalid.fpMask)
+	assert.Equal(t, len(large.buckets)/4, len(small.buckets), "slots must be packed at the fingerprint size")
+
+	for i := int64(0); i < 900; i++ {
+		small.Add(i)
+		large.Add(i)
+	}
+
+	assert.Greater(t, small.FalsePositiveRate(), large.FalsePositiveRate())
+
+	smallFP, largeFP := 0, 0
+
+	for i := int64(1000); i < 11_000; i++ {
+		if small.Contains(i) {
+			smallFP++
+		}
+
+		if large.Contains(i) {
+			largeFP++
+		}
+	}
+
+	assert.Greater(t, smallFP, largeFP)
+}
+
+func TestCuckooPackedSlots(t *testing.T) {
+	t.Parallel()
+
+	// widths that don't divide 64 have slots spanning two words
+	for _, fpBits := range []uint{4, 5, 7, 12, 13, 16} {
+		fpBits := fpBits
+		t.Run(strconv.FormatUint(uint64(fpBits), 10), func(t *testing.T) {
+			t.Parallel()
+
+			cf := NewInt64Cuckoo(1000, WithFingerprintBits(fpBits))
+
+			for i := int64(0); i < 900; i++ {
+				require.Truef(t, cf.Add(i), "Should add element %d", i)
+			}
+
+			for slot := range cf.slots() {
+				assert.LessOrEqual(t, uint64(cf.slot(slot)), cf.fpMask)
+			}
+
+			for i := int64(0); i < 900; i += 2 {
+				require.Truef(t, cf.Delete(i), "Should delete element %d", i)
+			}
+
+			for i := int64(1); i < 900; i += 2 {
+				assert.Truef(t, cf.Contains(i), "Delete must not cause false negatives, element %d", i)
+			}
+
+			assert.Equal(t, int64(450), cf.Count())
+		})
+	}
+}
+
+func TestCuckooFull(t *testing.T) {
+	t.Parallel()
+
+	cf := NewInt64Cuckoo(8)
+	added := make([]int64, 0)
+
+	for i := int64(0); cf.Add(i); i++ {
+		added = append(added, i)
+	}
+
+	assert.True(t, cf.victim.used)
+	assert.Equal(t, int64(len(added)), cf.Count())
+
+	for _, d := range added {
+		assert.Truef(t, cf.Contains(d), "Full filter must keep element %d", d)
+	}
+
+	// the victim only moves back when a slot frees up in one of its two buckets
+	for _, d := range added {
+		require.True(t, cf.Delete(d))
+
+		if !cf.victim.used {
+			break
+		}
+	}
+
+	assert.False(t, cf.victim.used)
+	assert.True(t, cf.Add(-1), "Delete must make room again")
+}
+
+func BenchmarkInt64Cuckoo(b *testing.B) {
+	cf := NewInt64Cuckoo(int64(b.N))
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		cf.Add(int64(i))
+		cf.Contains(int64(i))
+	}
+}
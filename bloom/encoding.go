@@ -0,0 +1,99 @@
+package bloom
+
+import (
+	"encoding"
+	"encoding/binary"
+	"hash/crc32"
+
+	"github.com/go-pantheon/fabrica-util/bitmap"
+	"github.com/go-pantheon/fabrica-util/errors"
+)
+
+// Binary layout of an encoded Int64BloomFilter, all header fields are big-endian:
+//
+//	magic    [2]byte "BF"
+//	version  uint8
+//	k        uint8  hash function count
+//	m        uint64 bit array size
+//	seed     int64  hash seed
+//	bitmap   []byte encoded bitmap.Bitmap without checksum
+//	checksum uint32 CRC-32C of all preceding bytes
+const (
+	encodingVersion    = 1
+	encodingHeaderSize = 20
+	checksumSize       = 4
+	maxHashFunctions   = 64
+)
+
+var (
+	// ErrInvalidEncoding is returned when the encoded data is malformed
+	ErrInvalidEncoding = errors.New("bloom: invalid encoding")
+	// ErrChecksumMismatch is returned when the checksum of the encoded data doesn't match
+	ErrChecksumMismatch = errors.New("bloom: checksum mismatch")
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*Int64BloomFilter)(nil)
+	_ encoding.BinaryUnmarshaler = (*Int64BloomFilter)(nil)
+
+	encodingMagic = [2]byte{'B', 'F'}
+	crcTable      = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler
+// The parameters m, k and the hash seed are embedded so the filter can be rebuilt anywhere
+func (bf *Int64BloomFilter) MarshalBinary() ([]byte, error) {
+	bits := bf.bitmap.Encode()
+
+	data := make([]byte, encodingHeaderSize, encodingHeaderSize+len(bits)+checksumSize)
+	copy(data, encodingMagic[:])
+	data[2] = encodingVersion
+	data[3] = byte(len(bf.hashFunc))
+	binary.BigEndian.PutUint64(data[4:], uint64(bf.size))
+	binary.BigEndian.PutUint64(data[12:], uint64(bf.seed))
+
+	data = append(data, bits...)
+	data = binary.BigEndian.AppendUint32(data, crc32.Checksum(data, crcTable))
+
+	return data, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing the parameters and content of the filter
+func (bf *Int64BloomFilter) UnmarshalBinary(data []byte) error {
+	if len(data) < encodingHeaderSize+checksumSize || data[0] != encodingMagic[0] || data[1] != encodingMagic[1] {
+		return errors.Wrap(ErrInvalidEncoding, "bad header")
+	}
+
+	if data[2] != encodingVersion {
+		return errors.Wrapf(ErrInvalidEncoding, "unsupported version %d", data[2])
+	}
+
+	body, sum := data[:len(data)-checksumSize], data[len(data)-checksumSize:]
+	if crc32.Checksum(body, crcTable) != binary.BigEndian.Uint32(sum) {
+		return ErrChecksumMismatch
+	}
+
+	k := int64(body[3])
+	if k == 0 || k > maxHashFunctions {
+		return errors.Wrapf(ErrInvalidEncoding, "invalid hash function count %d", k)
+	}
+
+	m := binary.BigEndian.Uint64(body[4:])
+	seed := int64(binary.BigEndian.Uint64(body[12:]))
+
+	bm := &bitmap.Bitmap{}
+	if err := bm.UnmarshalBinary(body[encodingHeaderSize:]); err != nil {
+		return errors.Wrapf(ErrInvalidEncoding, "decode bitmap failed: %v", err)
+	}
+
+	if m == 0 || uint64(bm.Size()) != m {
+		return errors.Wrapf(ErrInvalidEncoding, "bitmap size %d doesn't match m=%d", bm.Size(), m)
+	}
+
+	bf.bitmap = bm
+	bf.hashFunc = createInt64HashFunctions(k, seed)
+	bf.size = int64(m)
+	bf.seed = seed
+
+	return nil
+}
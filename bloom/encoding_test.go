@@ -0,0 +1,77 @@
+package bloom
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/go-pantheon/fabrica-util/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInt64BloomMarshalBinary(t *testing.T) {
+	t.Parallel()
+
+	bf := NewInt64Bloom(1000, 0.01, WithSeed(42))
+	for i := int64(0); i < 1000; i++ {
+		bf.Add(i * 3)
+	}
+
+	data, err := bf.MarshalBinary()
+	require.NoError(t, err)
+
+	got := &Int64BloomFilter{}
+	require.NoError(t, got.UnmarshalBinary(data))
+
+	assert.Equal(t, bf.size, got.size)
+	assert.Equal(t, bf.seed, got.seed)
+	assert.Len(t, got.hashFunc, len(bf.hashFunc))
+
+	for i := int64(0); i < 3000; i++ {
+		assert.Equalf(t, bf.Contains(i), got.Contains(i), "Contains(%d) mismatch", i)
+	}
+
+	// the decoded filter keeps working and stays mergeable with the original
+	got.Add(-1)
+	require.NoError(t, bf.Union(got))
+	assert.True(t, bf.Contains(-1))
+}
+
+func TestInt64BloomUnmarshalErrors(t *testing.T) {
+	t.Parallel()
+
+	bf := NewInt64Bloom(100, 0.01)
+
+	valid, err := bf.MarshalBinary()
+	require.NoError(t, err)
+
+	corrupt := func(i int, v byte) []byte {
+		data := slices.Clone(valid)
+		data[i] = v
+
+		return data
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"empty", nil, ErrInvalidEncoding},
+		{"bad magic", corrupt(0, 'X'), ErrInvalidEncoding},
+		{"bad version", corrupt(2, 9), ErrInvalidEncoding},
+		{"flipped bit", corrupt(encodingHeaderSize+20, 0xff), ErrChecksumMismatch},
+		{"truncated", valid[:len(valid)-1], ErrChecksumMismatch},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := NewInt64Bloom(10, 0.01)
+			err := got.UnmarshalBinary(tt.data)
+			assert.True(t, errors.Is(err, tt.wantErr), "unexpected error: %v", err)
+		})
+	}
+}
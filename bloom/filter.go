@@ -0,0 +1,97 @@
+package bloom
+
+import (
+	"math"
+
+	"github.com/go-pantheon/fabrica-util/bitmap"
+	"github.com/spaolacci/murmur3"
+)
+
+// Key is the constraint of the element types accepted by BloomFilter
+type Key interface {
+	~string | ~[]byte
+}
+
+// BloomFilter is a Bloom filter for string and byte slice elements such as usernames, device IDs
+// or message fingerprints. It derives all k bit indexes from the two halves of one 128-bit murmur3
+// hash with Kirsch–Mitzenmacher double hashing, so k has no upper limit and costs a single hash
+// computation.
+type BloomFilter[T Key] struct {
+	bitmap *bitmap.Bitmap
+	size   uint64
+	k      int64
+}
+
+// NewBloom create a Bloom filter for string or byte slice elements
+// n: expected element count
+// p: expected false positive rate (0 < p < 1)
+func NewBloom[T Key](n int64, p float64) *BloomFilter[T] {
+	m, k := estimateParameters(n, p)
+
+	return &BloomFilter[T]{
+		bitmap: bitmap.NewBitmap(m),
+		size:   uint64(m),
+		k:      k,
+	}
+}
+
+// Add add element
+func (bf *BloomFilter[T]) Add(data T) {
+	bf.bitmap.MSet(bf.locations(data))
+}
+
+// MAdd add multiple elements
+func (bf *BloomFilter[T]) MAdd(data []T) {
+	if len(data) == 0 {
+		return
+	}
+
+	indexes := make([]int64, 0, int64(len(data))*bf.k)
+	for _, d := range data {
+		indexes = append(indexes, bf.locations(d)...)
+	}
+
+	bf.bitmap.MSet(indexes)
+}
+
+// Contains check if the element may exist
+func (bf *BloomFilter[T]) Contains(data T) bool {
+	h1, h2 := murmur3.Sum128([]byte(data))
+
+	for i := range uint64(bf.k) {
+		if !bf.bitmap.IsSet(bf.location(h1, h2, i)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FillRatio returns the fraction of bits set to 1
+func (bf *BloomFilter[T]) FillRatio() float64 {
+	return float64(bf.bitmap.Count()) / float64(bf.size)
+}
+
+// FalsePositiveRate returns the current false positive probability estimated from the fill ratio
+func (bf *BloomFilter[T]) FalsePositiveRate() float64 {
+	return math.Pow(bf.FillRatio(), float64(bf.k))
+}
+
+// locations returns the k bit indexes of data
+func (bf *BloomFilter[T]) locations(data T) []int64 {
+	h1, h2 := murmur3.Sum128([]byte(data))
+
+	indexes := make([]int64, bf.k)
+	for i := range indexes {
+		indexes[i] = bf.location(h1, h2, uint64(i))
+	}
+
+	return indexes
+}
+
+// location returns the i-th bit index h1 + i*h2 + (i^3-i)/6 mod m
+// The cubic term (enhanced double hashing) avoids the short cycles plain h1 + i*h2 falls into
+// when h2 shares a factor with m, which noticeably raises the false positive rate for small p
+func (bf *BloomFilter[T]) location(h1, h2, i uint64) int64 {
+	return int64((h1 + i*h2 + (i*i*i-i)/6) % bf.size)
+}
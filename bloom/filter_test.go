@@ -0,0 +1,92 @@
+package bloom
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringBloomFilter(t *testing.T) {
+	t.Parallel()
+
+	const (
+		n = 10_000
+		p = 0.01
+	)
+
+	bf := NewBloom[string](n, p)
+
+	for i := 0; i < n; i++ {
+		bf.Add("user-" + strconv.Itoa(i))
+	}
+
+	for i := 0; i < n; i++ {
+		assert.Truef(t, bf.Contains("user-"+strconv.Itoa(i)), "Should contain added element %d", i)
+	}
+
+	falsePositives := 0
+	total := 100_000
+
+	for i := 0; i < total; i++ {
+		if bf.Contains("guest-" + strconv.Itoa(i)) {
+			falsePositives++
+		}
+	}
+
+	fpRate := float64(falsePositives) / float64(total)
+	assert.Less(t, fpRate, 2*p, "False positive rate too high: %f", fpRate)
+	assert.InDelta(t, p, bf.FalsePositiveRate(), p)
+}
+
+func TestBytesBloomFilter(t *testing.T) {
+	t.Parallel()
+
+	bf := NewBloom[[]byte](100, 0.01)
+	fingerprints := [][]byte{{}, {0}, {0, 0}, []byte("hello"), []byte("world")}
+
+	bf.MAdd(fingerprints)
+	bf.MAdd(nil)
+
+	for _, f := range fingerprints {
+		assert.True(t, bf.Contains(f))
+	}
+
+	assert.False(t, bf.Contains([]byte("hello world")))
+}
+
+func TestBloomFilterManyHashFunctions(t *testing.T) {
+	t.Parallel()
+
+	type DeviceID string
+
+	bf := NewBloom[DeviceID](1000, 1e-6)
+	assert.Greater(t, bf.k, int64(8), "k must not be capped")
+
+	for i := 0; i < 1000; i++ {
+		bf.Add(DeviceID("device-" + strconv.Itoa(i)))
+	}
+
+	// k distinct hash functions must spread every element over k bits
+	assert.Greater(t, bf.bitmap.Count(), int64(1000*8))
+
+	for i := 0; i < 100_000; i++ {
+		assert.False(t, bf.Contains(DeviceID("other-"+strconv.Itoa(i))))
+	}
+}
+
+func BenchmarkStringBloom(b *testing.B) {
+	bf := NewBloom[string](1000000, 0.01)
+
+	data := make([]string, 1024)
+	for i := range data {
+		data[i] = "user-" + strconv.Itoa(i)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		bf.Add(data[i%len(data)])
+		bf.Contains(data[i%len(data)])
+	}
+}
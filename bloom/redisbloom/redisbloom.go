@@ -0,0 +1,177 @@
+// Package redisbloom provides a Redis-backed int64 Bloom filter shared by every process using the same key
+package redisbloom
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-pantheon/fabrica-util/bitmap/redisbitmap"
+	"github.com/go-pantheon/fabrica-util/bloom"
+	"github.com/go-pantheon/fabrica-util/errors"
+	"github.com/go-pantheon/fabrica-util/internal/hashmix"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// addScript sets the bits given in ARGV and returns how many of them were 0 before
+	addScript = redis.NewScript(`
+local added = 0
+for i = 1, #ARGV do
+	added = added + 1 - redis.call('SETBIT', KEYS[1], ARGV[i], 1)
+end
+return added
+`)
+
+	// containsScript returns 1 if all bits given in ARGV are set, stopping at the first 0
+	containsScript = redis.NewScript(`
+for i = 1, #ARGV do
+	if redis.call('GETBIT', KEYS[1], ARGV[i]) == 0 then
+		return 0
+	end
+end
+return 1
+`)
+)
+
+// Filter is an int64 Bloom filter whose bits are stored in Redis strings
+// It hashes exactly like bloom.Int64BloomFilter, and every Add or Contains is a single Lua script
+// call, so it costs one round trip. With WithShards the filter is split into several keys placed
+// in different cluster slots, each element living entirely in the shard its hash picks.
+type Filter struct {
+	client redis.UniversalClient
+	keys   []string
+	hasher *bloom.Int64Hasher
+	seed   int64
+}
+
+// Option define the type of the filter option function
+type Option func(*Filter)
+
+// WithSeed set the hash seed, it must match bloom.WithSeed to share the hashing of a local filter
+func WithSeed(seed int64) Option {
+	return func(f *Filter) {
+		f.seed = seed
+	}
+}
+
+// WithShards split the filter into n keys, n <= 1 keeps a single key
+// Shard keys are {key:i}, the hash tag spreads the shards of every filter over different cluster
+// slots. The key must not contain braces, a hash tag in it would put all shards in one slot.
+func WithShards(n int) Option {
+	return func(f *Filter) {
+		if n <= 1 {
+			f.keys = f.keys[:1]
+			return
+		}
+
+		base := f.keys[0]
+
+		f.keys = make([]string, n)
+		for i := range f.keys {
+			f.keys[i] = fmt.Sprintf("{%s:%d}", base, i)
+		}
+	}
+}
+
+// New create a Redis-backed int64 Bloom filter stored under key
+// n: expected element count
+// p: expected false positive rate (0 < p < 1)
+// Each shard is sized for its share of n with the false positive rate p
+func New(client redis.UniversalClient, key string, n int64, p float64, opts ...Option) *Filter {
+	f := &Filter{
+		client: client,
+		keys:   []string{key},
+	}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	shards := int64(len(f.keys))
+
+	m, k := bloom.Int64Parameters((n+shards-1)/shards, p)
+	if m > redisbitmap.MaxSize {
+		panic("bloom shard size exceeds redisbitmap.MaxSize, use more shards")
+	}
+
+	f.hasher = bloom.NewInt64Hasher(m, k, f.seed)
+
+	return f
+}
+
+// Add add int64 element, returns true if the element was definitely not in the filter before
+func (f *Filter) Add(ctx context.Context, data int64) (bool, error) {
+	key := f.shard(data)
+
+	added, err := addScript.Run(ctx, f.client, []string{key}, f.args(data)...).Int64()
+	if err != nil {
+		return false, errors.Wrapf(err, "redis bloom add failed. key=%s", key)
+	}
+
+	return added > 0, nil
+}
+
+// MAdd add multiple int64 elements, with one script call per touched shard sent in one pipeline
+func (f *Filter) MAdd(ctx context.Context, data []int64) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	args := make(map[string][]any, len(f.keys))
+	for _, d := range data {
+		key := f.shard(d)
+		args[key] = append(args[key], f.args(d)...)
+	}
+
+	_, err := f.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, a := range args {
+			// EVALSHA can't fall back to EVAL inside a pipeline, send the script body instead
+			addScript.Eval(ctx, pipe, []string{key}, a...)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "redis pipelined bloom add failed. key=%s", f.keys[0])
+	}
+
+	return nil
+}
+
+// Contains check if the element may exist
+func (f *Filter) Contains(ctx context.Context, data int64) (bool, error) {
+	key := f.shard(data)
+
+	ok, err := containsScript.Run(ctx, f.client, []string{key}, f.args(data)...).Int64()
+	if err != nil {
+		return false, errors.Wrapf(err, "redis bloom contains failed. key=%s", key)
+	}
+
+	return ok == 1, nil
+}
+
+// Keys returns the Redis keys holding the shards of the filter
+func (f *Filter) Keys() []string {
+	return f.keys
+}
+
+// shard returns the key of the shard data belongs to
+// It uses its own hash so that the choice is independent from the bit locations inside the shard
+func (f *Filter) shard(data int64) string {
+	if len(f.keys) == 1 {
+		return f.keys[0]
+	}
+
+	return f.keys[hashmix.Mix64(uint64(data^f.seed))%uint64(len(f.keys))]
+}
+
+func (f *Filter) args(data int64) []any {
+	locations := f.hasher.Locations(data)
+
+	args := make([]any, len(locations))
+	for i, l := range locations {
+		args[i] = l
+	}
+
+	return args
+}
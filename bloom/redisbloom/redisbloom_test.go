@@ -0,0 +1,143 @@
+package redisbloom
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-pantheon/fabrica-util/bloom"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	_, client := newClient(t)
+	f := New(client, "registered", 1000, 0.01)
+
+	added, err := f.Add(ctx, 42)
+	require.NoError(t, err)
+	assert.True(t, added)
+
+	added, err = f.Add(ctx, 42)
+	require.NoError(t, err)
+	assert.False(t, added, "second add of the same element")
+
+	ok, err := f.Contains(ctx, 42)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = f.Contains(ctx, 43)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, f.MAdd(ctx, []int64{-1, 0, 1 << 40}))
+	require.NoError(t, f.MAdd(ctx, nil))
+
+	for _, i := range []int64{-1, 0, 1 << 40} {
+		ok, err = f.Contains(ctx, i)
+		require.NoError(t, err)
+		assert.Truef(t, ok, "Element %d not found", i)
+	}
+}
+
+func TestSameHashingAsLocal(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	mr, client := newClient(t)
+	remote := New(client, "users", 500, 0.05, WithSeed(99))
+	local := bloom.NewInt64Bloom(500, 0.05, bloom.WithSeed(99))
+
+	data := make([]int64, 0, 500)
+	for i := range int64(500) {
+		data = append(data, i*7919)
+	}
+
+	require.NoError(t, remote.MAdd(ctx, data))
+	local.MAdd(data)
+
+	// false positives included, remote and local must answer every probe the same
+	for i := range int64(5000) {
+		ok, err := remote.Contains(ctx, i)
+		require.NoError(t, err)
+		assert.Equalf(t, local.Contains(i), ok, "Element %d", i)
+	}
+
+	assert.True(t, mr.Exists("users"))
+}
+
+func TestShards(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	mr, client := newClient(t)
+	f := New(client, "devices", 10000, 0.01, WithShards(4))
+
+	require.Equal(t, []string{"{devices:0}", "{devices:1}", "{devices:2}", "{devices:3}"}, f.Keys())
+
+	data := make([]int64, 0, 10000)
+	for i := range int64(10000) {
+		data = append(data, i)
+	}
+
+	require.NoError(t, f.MAdd(ctx, data))
+
+	for _, key := range f.Keys() {
+		assert.Truef(t, mr.Exists(key), "Shard %s is empty", key)
+	}
+
+	for _, i := range data {
+		ok, err := f.Contains(ctx, i)
+		require.NoError(t, err)
+		require.Truef(t, ok, "Element %d not found", i)
+	}
+
+	falsePositives := 0
+
+	for i := int64(10000); i < 20000; i++ {
+		ok, err := f.Contains(ctx, i)
+		require.NoError(t, err)
+
+		if ok {
+			falsePositives++
+		}
+	}
+
+	assert.Less(t, float64(falsePositives)/10000, 0.02)
+
+	assert.Equal(t, []string{"single"}, New(client, "single", 10, 0.01, WithShards(1)).Keys())
+}
+
+func TestContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, client := newClient(t)
+	f := New(client, "canceled", 10, 0.01)
+
+	_, err := f.Add(ctx, 1)
+	assert.Error(t, err)
+
+	_, err = f.Contains(ctx, 1)
+	assert.Error(t, err)
+
+	assert.Error(t, f.MAdd(ctx, []int64{1}))
+}
+
+// newClient starts a miniredis server standing in for Redis, it runs the Lua scripts of the filter
+func newClient(t *testing.T) (*miniredis.Miniredis, redis.UniversalClient) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return mr, client
+}
@@ -0,0 +1,168 @@
+package bloom
+
+import (
+	"math"
+	"sync"
+)
+
+const (
+	// DefaultGrowth is the default capacity multiplier of each new slice of a scalable filter
+	DefaultGrowth = 2
+	// DefaultTightening is the default error ratio between two consecutive slices of a scalable filter
+	DefaultTightening = 0.8
+)
+
+// ScalableInt64BloomFilter is a Bloom filter for int64 that keeps its false positive rate bounded
+// while growing past its initial capacity. Once the current slice holds its capacity, a new slice
+// with a larger capacity and a tighter error rate is added, so the compound false positive rate
+// converges to p however many elements are added.
+type ScalableInt64BloomFilter struct {
+	mutex sync.RWMutex
+
+	slices     []*Int64BloomFilter
+	capacities []int64
+	counts     []int64
+
+	p          float64
+	growth     int64
+	tightening float64
+}
+
+// ScalableOption define the type of the scalable filter option function
+type ScalableOption func(*ScalableInt64BloomFilter)
+
+// WithGrowth set the capacity multiplier of each new slice, must be at least 1
+func WithGrowth(growth int64) ScalableOption {
+	return func(bf *ScalableInt64BloomFilter) {
+		if growth >= 1 {
+			bf.growth = growth
+		}
+	}
+}
+
+// WithTightening set the error ratio between two consecutive slices, must be in (0, 1)
+func WithTightening(r float64) ScalableOption {
+	return func(bf *ScalableInt64BloomFilter) {
+		if r > 0 && r < 1 {
+			bf.tightening = r
+		}
+	}
+}
+
+// NewScalableInt64Bloom create a scalable int64 Bloom filter
+// n: initial expected element count
+// p: upper bound of the compound false positive rate (0 < p < 1)
+func NewScalableInt64Bloom(n int64, p float64, opts ...ScalableOption) *ScalableInt64BloomFilter {
+	bf := &ScalableInt64BloomFilter{
+		p:          p,
+		growth:     DefaultGrowth,
+		tightening: DefaultTightening,
+	}
+
+	for _, opt := range opts {
+		opt(bf)
+	}
+
+	// the error rates p0, p0*r, p0*r^2... sum up to p0/(1-r) = p
+	bf.addSlice(max(n, 1), p*(1-bf.tightening))
+
+	return bf
+}
+
+// Add add int64 element, a new slice is created when the current one is full
+func (bf *ScalableInt64BloomFilter) Add(data int64) {
+	bf.mutex.Lock()
+	defer bf.mutex.Unlock()
+
+	bf.add(data)
+}
+
+// MAdd add multiple int64 elements
+func (bf *ScalableInt64BloomFilter) MAdd(data []int64) {
+	if len(data) == 0 {
+		return
+	}
+
+	bf.mutex.Lock()
+	defer bf.mutex.Unlock()
+
+	for _, d := range data {
+		bf.add(d)
+	}
+}
+
+// Contains check if the element may exist in any slice
+func (bf *ScalableInt64BloomFilter) Contains(data int64) bool {
+	bf.mutex.RLock()
+	defer bf.mutex.RUnlock()
+
+	return bf.contains(data)
+}
+
+// FillRatio returns the fraction of bits set to 1 across all slices
+func (bf *ScalableInt64BloomFilter) FillRatio() float64 {
+	bf.mutex.RLock()
+	defer bf.mutex.RUnlock()
+
+	set, total := int64(0), int64(0)
+
+	for _, s := range bf.slices {
+		set += s.bitmap.Count()
+		total += s.size
+	}
+
+	return float64(set) / float64(total)
+}
+
+// FalsePositiveRate returns the current compound false positive probability, 1 - Π(1 - p_i) over all slices
+func (bf *ScalableInt64BloomFilter) FalsePositiveRate() float64 {
+	bf.mutex.RLock()
+	defer bf.mutex.RUnlock()
+
+	miss := 1.0
+	for _, s := range bf.slices {
+		miss *= 1 - s.FalsePositiveRate()
+	}
+
+	return 1 - miss
+}
+
+// Slices returns the number of slices created so far
+func (bf *ScalableInt64BloomFilter) Slices() int {
+	bf.mutex.RLock()
+	defer bf.mutex.RUnlock()
+
+	return len(bf.slices)
+}
+
+func (bf *ScalableInt64BloomFilter) add(data int64) {
+	// elements that may already exist are not counted again, so duplicates don't grow the filter
+	if bf.contains(data) {
+		return
+	}
+
+	last := len(bf.slices) - 1
+	if bf.counts[last] >= bf.capacities[last] {
+		bf.addSlice(bf.capacities[last]*bf.growth, bf.p*(1-bf.tightening)*math.Pow(bf.tightening, float64(last+1)))
+		last++
+	}
+
+	bf.slices[last].Add(data)
+	bf.counts[last]++
+}
+
+func (bf *ScalableInt64BloomFilter) contains(data int64) bool {
+	for i := len(bf.slices) - 1; i >= 0; i-- {
+		if bf.slices[i].Contains(data) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (bf *ScalableInt64BloomFilter) addSlice(n int64, p float64) {
+	bf.slices = append(bf.slices, NewInt64Bloom(n, p))
+	bf.capacities = append(bf.capacities, n)
+	bf.counts = append(bf.counts, 0)
+}
@@ -0,0 +1,93 @@
+package bloom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScalableInt64BloomFilter(t *testing.T) {
+	t.Parallel()
+
+	const (
+		n     = 1000
+		added = 20 * n
+		p     = 0.01
+	)
+
+	bf := NewScalableInt64Bloom(n, p)
+	assert.Equal(t, 1, bf.Slices())
+
+	for i := int64(0); i < added; i++ {
+		bf.Add(i)
+	}
+
+	assert.Greater(t, bf.Slices(), 1, "filter should have grown")
+
+	for i := int64(0); i < added; i++ {
+		assert.Truef(t, bf.Contains(i), "Should contain added element %d", i)
+	}
+
+	falsePositives := 0
+	total := 100_000
+
+	r := newRand()
+
+	for i := 0; i < total; i++ {
+		if bf.Contains(added + r.Int64N(1<<40)) {
+			falsePositives++
+		}
+	}
+
+	fpRate := float64(falsePositives) / float64(total)
+	assert.Less(t, fpRate, 2*p, "False positive rate too high: %f", fpRate)
+	assert.Less(t, bf.FalsePositiveRate(), 2*p)
+	assert.Greater(t, bf.FillRatio(), 0.0)
+	assert.Less(t, bf.FillRatio(), 1.0)
+
+	t.Run("classic filter degrades", func(t *testing.T) {
+		t.Parallel()
+
+		classic := NewInt64Bloom(n, p)
+		for i := int64(0); i < added; i++ {
+			classic.Add(i)
+		}
+
+		assert.Greater(t, classic.FalsePositiveRate(), 10*p)
+		assert.Greater(t, classic.FillRatio(), 0.9)
+	})
+}
+
+func TestScalableOptions(t *testing.T) {
+	t.Parallel()
+
+	bf := NewScalableInt64Bloom(10, 0.01, WithGrowth(4), WithTightening(0.5))
+	assert.Equal(t, int64(4), bf.growth)
+	assert.Equal(t, 0.5, bf.tightening)
+
+	bf.MAdd([]int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11})
+	assert.Equal(t, 2, bf.Slices())
+	assert.Equal(t, int64(40), bf.capacities[1])
+
+	// duplicates are not counted against the capacity
+	for i := 0; i < 100; i++ {
+		bf.Add(11)
+	}
+
+	assert.Equal(t, 2, bf.Slices())
+
+	invalid := NewScalableInt64Bloom(10, 0.01, WithGrowth(0), WithTightening(1))
+	assert.Equal(t, int64(DefaultGrowth), invalid.growth)
+	assert.Equal(t, DefaultTightening, invalid.tightening)
+}
+
+func BenchmarkScalableInt64Bloom(b *testing.B) {
+	bf := NewScalableInt64Bloom(1000, 0.01)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		bf.Add(int64(i))
+		bf.Contains(int64(i))
+	}
+}
@@ -0,0 +1,233 @@
+package compress
+
+import (
+	"math"
+	"sync"
+)
+
+const (
+	// DefaultMinGain is the default minimum fraction of the size compression must save
+	DefaultMinGain = 0.1
+	// DefaultMaxEntropy is the default sample entropy in bits per byte above which data is
+	// considered incompressible, encrypted or already compressed data is close to 8
+	DefaultMaxEntropy = 7.5
+	// DefaultEntropySample is the default number of leading bytes the entropy is measured on
+	DefaultEntropySample = 4 << 10
+
+	// categoryAlpha is the weight of the latest ratio in the moving average of a category
+	categoryAlpha = 0.2
+	// categoryWarmup is the number of compressed calls before a category may be skipped
+	categoryWarmup = 4
+	// categoryProbe makes one call out of categoryProbe compress anyway, so that the ratio of a
+	// skipped category keeps up with its payloads
+	categoryProbe = 16
+)
+
+// Reason tells why CompressAdaptive did or didn't compress
+type Reason int
+
+const (
+	// ReasonCompressed means the data was compressed
+	ReasonCompressed Reason = iota
+	// ReasonBelowThreshold means the data is shorter than the weak threshold
+	ReasonBelowThreshold
+	// ReasonHighEntropy means the sampled entropy is above the max entropy
+	ReasonHighEntropy
+	// ReasonCategoryRatio means the recent ratio of the category saves less than the min gain
+	ReasonCategoryRatio
+	// ReasonLowGain means the data was compressed but saved less than the min gain, so it was dropped
+	ReasonLowGain
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonCompressed:
+		return "compressed"
+	case ReasonBelowThreshold:
+		return "below threshold"
+	case ReasonHighEntropy:
+		return "high entropy"
+	case ReasonCategoryRatio:
+		return "category ratio"
+	case ReasonLowGain:
+		return "low gain"
+	default:
+		return "unknown"
+	}
+}
+
+// Decision reports what CompressAdaptive did
+type Decision struct {
+	Reason Reason
+	// Entropy is the sampled entropy in bits per byte, 0 if the data wasn't sampled
+	Entropy float64
+	// Ratio is the compressed / original size of this call, or the recent ratio of the category
+	// when it was skipped for ReasonCategoryRatio
+	Ratio float64
+}
+
+// Compressed reports whether the returned data is compressed
+func (d Decision) Compressed() bool {
+	return d.Reason == ReasonCompressed
+}
+
+// WithMinGain set the minimum fraction of the size CompressAdaptive must save, between 0 and 1
+// Compressing 100 bytes into 95 saves 0.05
+func WithMinGain(gain float64) Option {
+	return func(c *Compressor) {
+		if gain >= 0 && gain < 1 {
+			c.minGain = gain
+		}
+	}
+}
+
+// WithEntropyLimit set the max entropy in bits per byte and the sample size it is measured on
+// A max entropy of 8 or more disables the entropy check
+func WithEntropyLimit(maxEntropy float64, sampleSize int) Option {
+	return func(c *Compressor) {
+		if maxEntropy > 0 && sampleSize > 0 {
+			c.maxEntropy = maxEntropy
+			c.sampleSize = sampleSize
+		}
+	}
+}
+
+// CompressAdaptive compresses data only when it is likely to pay off
+// It skips data whose leading bytes look random, and data of a category whose recent payloads
+// compressed poorly. category is chosen by the caller from a small fixed set, such as the message
+// type, "" disables the category tracking. Data saving less than the min gain is returned as is.
+// return the data to send, the decision taken, error info
+func (c *Compressor) CompressAdaptive(category string, data []byte) (ret []byte, decision Decision, err error) {
+	if len(data) == 0 {
+		return []byte{}, Decision{Reason: ReasonBelowThreshold}, nil
+	}
+
+	if int64(len(data)) < c.weakThreshold.Load() {
+		c.skipped.Add(1)
+		return data, Decision{Reason: ReasonBelowThreshold}, nil
+	}
+
+	var cat *categoryStats
+
+	if category != "" {
+		v, _ := c.categories.LoadOrStore(category, &categoryStats{})
+		cat = v.(*categoryStats)
+
+		if ratio, skip := cat.skip(1 - c.minGain); skip {
+			c.skipped.Add(1)
+			return data, Decision{Reason: ReasonCategoryRatio, Ratio: ratio}, nil
+		}
+	}
+
+	if c.maxEntropy < 8 {
+		decision.Entropy = entropy(data[:min(len(data), c.sampleSize)])
+
+		if decision.Entropy > c.maxEntropy {
+			c.skipped.Add(1)
+			cat.observe(1)
+
+			decision.Reason = ReasonHighEntropy
+
+			return data, decision, nil
+		}
+	}
+
+	if ret, err = c.encode(c.codec, nil, data, !isZlib(c.codec)); err != nil {
+		return nil, decision, err
+	}
+
+	decision.Ratio = float64(len(ret)) / float64(len(data))
+	cat.observe(decision.Ratio)
+
+	if decision.Ratio > 1-c.minGain {
+		c.skipped.Add(1)
+
+		decision.Reason = ReasonLowGain
+
+		return data, decision, nil
+	}
+
+	c.record(len(data), len(ret))
+
+	decision.Reason = ReasonCompressed
+
+	return ret, decision, nil
+}
+
+// CategoryRatio returns the recent compressed / original size ratio of a category
+func (c *Compressor) CategoryRatio(category string) (float64, bool) {
+	v, ok := c.categories.Load(category)
+	if !ok {
+		return 0, false
+	}
+
+	cat := v.(*categoryStats)
+
+	cat.mutex.Lock()
+	defer cat.mutex.Unlock()
+
+	return cat.ratio, cat.observed > 0
+}
+
+// categoryStats tracks the moving average of the ratios of one payload category
+type categoryStats struct {
+	mutex    sync.Mutex
+	ratio    float64
+	observed int
+	skipped  int
+}
+
+// skip reports whether the next call should be skipped because the ratio is above maxRatio
+func (cat *categoryStats) skip(maxRatio float64) (float64, bool) {
+	cat.mutex.Lock()
+	defer cat.mutex.Unlock()
+
+	if cat.observed < categoryWarmup || cat.ratio <= maxRatio {
+		return cat.ratio, false
+	}
+
+	cat.skipped++
+	if cat.skipped%categoryProbe == 0 {
+		return cat.ratio, false
+	}
+
+	return cat.ratio, true
+}
+
+// observe adds a ratio to the moving average, nil categories are ignored
+func (cat *categoryStats) observe(ratio float64) {
+	if cat == nil {
+		return
+	}
+
+	cat.mutex.Lock()
+	defer cat.mutex.Unlock()
+
+	if cat.observed == 0 {
+		cat.ratio = ratio
+	} else {
+		cat.ratio += categoryAlpha * (ratio - cat.ratio)
+	}
+
+	cat.observed++
+}
+
+// entropy returns the Shannon entropy of data in bits per byte
+func entropy(data []byte) float64 {
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+
+	n := float64(len(data))
+	h := 0.0
+
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / n
+			h -= p * math.Log2(p)
+		}
+	}
+
+	return h
+}
@@ -0,0 +1,106 @@
+package compress
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressAdaptive(t *testing.T) {
+	t.Parallel()
+
+	json := bytes.Repeat([]byte(`{"id":1,"name":"config","enabled":true}`), 1000)
+	random := randBytes(64 << 10)
+
+	// random data after a compressible prefix passes the entropy check but not the gain check
+	mixed := append(bytes.Repeat([]byte{'a'}, DefaultEntropySample), randBytes(64<<10)...)
+
+	tests := []struct {
+		name   string
+		opts   []Option
+		data   []byte
+		reason Reason
+	}{
+		{"Compressed", nil, json, ReasonCompressed},
+		{"BelowThreshold", nil, json[:100], ReasonBelowThreshold},
+		{"Empty", nil, nil, ReasonBelowThreshold},
+		{"HighEntropy", nil, random, ReasonHighEntropy},
+		{"LowGain", nil, mixed, ReasonLowGain},
+		{"NoEntropyCheck", []Option{WithEntropyLimit(8, 1), WithMinGain(0)}, random, ReasonLowGain},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := NewCompressor(append([]Option{WithThresholds(1<<10, 1<<20)}, tt.opts...)...)
+
+			ret, decision, err := c.CompressAdaptive("", tt.data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.reason, decision.Reason, decision.Reason.String())
+			assert.Equal(t, tt.reason == ReasonCompressed, decision.Compressed())
+
+			if !decision.Compressed() {
+				assert.Equal(t, len(tt.data), len(ret))
+				return
+			}
+
+			assert.Less(t, decision.Ratio, 0.1)
+
+			decompressed, err := c.Decompress(ret)
+			require.NoError(t, err)
+			assert.Equal(t, tt.data, decompressed)
+		})
+	}
+}
+
+func TestCompressAdaptiveCategory(t *testing.T) {
+	t.Parallel()
+
+	c := NewCompressor(WithThresholds(1<<10, 1<<20), WithEntropyLimit(8, 1))
+	config := bytes.Repeat([]byte(`{"id":1,"name":"config"}`), 1000)
+
+	reasons := make(map[Reason]int)
+
+	for range 100 {
+		_, decision, err := c.CompressAdaptive("aes", randBytes(8<<10))
+		require.NoError(t, err)
+
+		reasons[decision.Reason]++
+
+		_, decision, err = c.CompressAdaptive("config", config)
+		require.NoError(t, err)
+		assert.True(t, decision.Compressed(), "the config category must not be affected")
+	}
+
+	// after the warm-up only one call out of categoryProbe still tries
+	assert.Greater(t, reasons[ReasonCategoryRatio], 80)
+	assert.Equal(t, 100, reasons[ReasonCategoryRatio]+reasons[ReasonLowGain])
+
+	ratio, ok := c.CategoryRatio("aes")
+	require.True(t, ok)
+	assert.Greater(t, ratio, 1-DefaultMinGain)
+
+	ratio, ok = c.CategoryRatio("config")
+	require.True(t, ok)
+	assert.Less(t, ratio, 0.1)
+
+	_, ok = c.CategoryRatio("unknown")
+	assert.False(t, ok)
+
+	stats := c.GetStats()
+	assert.Equal(t, int64(100), stats.Compressed)
+	assert.Equal(t, int64(100), stats.Skipped)
+}
+
+func TestEntropy(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 0.0, entropy(bytes.Repeat([]byte{'a'}, 100)))
+	assert.InDelta(t, 1.0, entropy([]byte("abababab")), 1e-9)
+	assert.Greater(t, entropy(randBytes(DefaultEntropySample)), DefaultMaxEntropy)
+	assert.Less(t, entropy(protoMessage(1)), DefaultMaxEntropy)
+}
@@ -0,0 +1,360 @@
+package compress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/go-pantheon/fabrica-util/errors"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// Codec IDs of the built-in codecs, written as the first byte of the compressed data
+const (
+	CodecZlib   byte = 1
+	CodecGzip   byte = 2
+	CodecZstd   byte = 3
+	CodecSnappy byte = 4
+	CodecLZ4    byte = 5
+)
+
+const (
+	// maxCodecID is the largest codec ID, the high bit of the header byte is reserved for flags
+	maxCodecID = 0x7f
+	// zlibCM is the low nibble of the first byte of a zlib stream (compression method deflate),
+	// IDs ending with it are reserved so that headerless legacy zlib data stays recognizable
+	zlibCM = 0x08
+)
+
+// Level is a codec-agnostic compression level, every codec maps it to its own scale
+type Level int
+
+const (
+	// LevelDefault balances speed and ratio
+	LevelDefault Level = iota
+	// LevelFastest favors speed
+	LevelFastest
+	// LevelBest favors ratio
+	LevelBest
+)
+
+var (
+	// ErrUnknownCodec is returned when the header of the compressed data names no registered codec
+	ErrUnknownCodec = errors.New("compress: unknown codec")
+
+	errClosed = errors.New("compress: use of closed compressor")
+)
+
+// Codec is a compression algorithm identified by a one-byte ID in the compressed data header
+type Codec interface {
+	// ID returns the header byte of the codec, between 1 and 0x7f
+	ID() byte
+	// Name returns the human readable name of the codec
+	Name() string
+	// NewWriter returns a writer compressing into w, the data is complete only after Close
+	NewWriter(w io.Writer, level Level) (io.WriteCloser, error)
+	// NewReader returns a reader decompressing from r
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// Built-in codecs, all registered at init
+var (
+	Zlib   Codec = zlibCodec{}
+	Gzip   Codec = gzipCodec{}
+	Zstd   Codec = zstdCodec{}
+	Snappy Codec = snappyCodec{}
+	LZ4    Codec = lz4Codec{}
+)
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[byte]Codec{}
+)
+
+func init() {
+	for _, c := range []Codec{Zlib, Gzip, Zstd, Snappy, LZ4} {
+		Register(c)
+	}
+}
+
+// Register makes a codec available to Decompress by its ID
+// It panics if the ID is reserved or already registered
+func Register(c Codec) {
+	if c == nil {
+		panic("compress: Register codec is nil")
+	}
+
+	id := c.ID()
+	if id == 0 || id > maxCodecID || id&0x0f == zlibCM {
+		panic("compress: codec ID is reserved")
+	}
+
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	if _, ok := codecs[id]; ok {
+		panic("compress: Register called twice for codec " + c.Name())
+	}
+
+	codecs[id] = c
+}
+
+// Lookup returns the codec registered with the ID
+func Lookup(id byte) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	c, ok := codecs[id]
+
+	return c, ok
+}
+
+// codecOf returns the codec and dictionary of compressed data and the offset of its payload
+// Data without a header is zlib written before codecs existed
+func codecOf(data []byte) (Codec, *Dict, int, error) {
+	if isLegacyZlib(data) {
+		return Zlib, nil, 0, nil
+	}
+
+	c, ok := Lookup(data[0] &^ dictFlag)
+	if !ok {
+		return nil, nil, 0, errors.Wrapf(ErrUnknownCodec, "id=%d", data[0]&^dictFlag)
+	}
+
+	if data[0]&dictFlag == 0 {
+		return c, nil, 1, nil
+	}
+
+	if len(data) < 1+dictIDSize {
+		return nil, nil, 0, errors.Wrap(ErrUnknownDict, "truncated dictionary ID")
+	}
+
+	id := binary.BigEndian.Uint32(data[1:])
+
+	d, ok := LookupDict(id)
+	if !ok {
+		return nil, nil, 0, errors.Wrapf(ErrUnknownDict, "id=%d", id)
+	}
+
+	return c, d, 1 + dictIDSize, nil
+}
+
+// isLegacyZlib checks the zlib CMF and FLG bytes, CMF*256+FLG is a multiple of 31
+func isLegacyZlib(data []byte) bool {
+	return len(data) >= 2 && data[0]&0x0f == zlibCM && (uint16(data[0])<<8|uint16(data[1]))%31 == 0
+}
+
+// zlibCodec reuses pooled zlib writers and readers through Reset, the zlib state is large
+type zlibCodec struct{}
+
+var (
+	zlibWriterPools [LevelBest + 1]sync.Pool
+	zlibReaderPool  sync.Pool
+)
+
+func (zlibCodec) ID() byte     { return CodecZlib }
+func (zlibCodec) Name() string { return "zlib" }
+
+func (zlibCodec) NewWriter(w io.Writer, level Level) (io.WriteCloser, error) {
+	if level < LevelDefault || level > LevelBest {
+		level = LevelDefault
+	}
+
+	pool := &zlibWriterPools[level]
+
+	if zw, ok := pool.Get().(*zlib.Writer); ok {
+		zw.Reset(w)
+		return &pooledZlibWriter{zw: zw, pool: pool}, nil
+	}
+
+	zw, err := zlib.NewWriterLevel(w, flateLevel(level))
+	if err != nil {
+		return nil, err
+	}
+
+	return &pooledZlibWriter{zw: zw, pool: pool}, nil
+}
+
+func (zlibCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	if zr, ok := zlibReaderPool.Get().(io.ReadCloser); ok {
+		if err := zr.(zlib.Resetter).Reset(r, nil); err != nil {
+			zlibReaderPool.Put(zr)
+			return nil, err
+		}
+
+		return &pooledZlibReader{ReadCloser: zr}, nil
+	}
+
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pooledZlibReader{ReadCloser: zr}, nil
+}
+
+func (zlibCodec) NewDictWriter(w io.Writer, level Level, d *Dict) (io.WriteCloser, error) {
+	return zlib.NewWriterLevelDict(w, flateLevel(level), d.Data)
+}
+
+func (zlibCodec) NewDictReader(r io.Reader, d *Dict) (io.ReadCloser, error) {
+	return zlib.NewReaderDict(r, d.Data)
+}
+
+// pooledZlibWriter puts the zlib writer back to its pool on Close
+type pooledZlibWriter struct {
+	zw   *zlib.Writer
+	pool *sync.Pool
+}
+
+func (w *pooledZlibWriter) Write(p []byte) (int, error) {
+	if w.zw == nil {
+		return 0, errClosed
+	}
+
+	return w.zw.Write(p)
+}
+
+func (w *pooledZlibWriter) Close() error {
+	if w.zw == nil {
+		return nil
+	}
+
+	err := w.zw.Close()
+	w.pool.Put(w.zw)
+	w.zw = nil
+
+	return err
+}
+
+// pooledZlibReader puts the zlib reader back to the pool on Close
+type pooledZlibReader struct {
+	io.ReadCloser
+}
+
+func (r *pooledZlibReader) Close() error {
+	if r.ReadCloser == nil {
+		return nil
+	}
+
+	err := r.ReadCloser.Close()
+	zlibReaderPool.Put(r.ReadCloser)
+	r.ReadCloser = nil
+
+	return err
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) ID() byte     { return CodecGzip }
+func (gzipCodec) Name() string { return "gzip" }
+
+func (gzipCodec) NewWriter(w io.Writer, level Level) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, flateLevel(level))
+}
+
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// flateLevel maps a Level to the levels shared by zlib and gzip
+func flateLevel(level Level) int {
+	switch level {
+	case LevelFastest:
+		return zlib.BestSpeed
+	case LevelBest:
+		return zlib.BestCompression
+	default:
+		return zlib.DefaultCompression
+	}
+}
+
+type zstdCodec struct{}
+
+func (zstdCodec) ID() byte     { return CodecZstd }
+func (zstdCodec) Name() string { return "zstd" }
+
+func (zstdCodec) NewWriter(w io.Writer, level Level) (io.WriteCloser, error) {
+	// messages are small, one goroutine avoids the cost of the concurrent encoder
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(zstdLevel(level)), zstd.WithEncoderConcurrency(1))
+}
+
+func (zstdCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+
+	return d.IOReadCloser(), nil
+}
+
+// NewDictWriter uses d as a raw content dictionary, the dictionary ID is also written in the zstd frame
+func (zstdCodec) NewDictWriter(w io.Writer, level Level, d *Dict) (io.WriteCloser, error) {
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(zstdLevel(level)), zstd.WithEncoderConcurrency(1),
+		zstd.WithEncoderDictRaw(d.ID, d.Data))
+}
+
+func (zstdCodec) NewDictReader(r io.Reader, d *Dict) (io.ReadCloser, error) {
+	dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderDictRaw(d.ID, d.Data))
+	if err != nil {
+		return nil, err
+	}
+
+	return dec.IOReadCloser(), nil
+}
+
+func zstdLevel(level Level) zstd.EncoderLevel {
+	switch level {
+	case LevelFastest:
+		return zstd.SpeedFastest
+	case LevelBest:
+		return zstd.SpeedBestCompression
+	default:
+		return zstd.SpeedDefault
+	}
+}
+
+// snappyCodec uses the snappy framing format, snappy has no levels
+type snappyCodec struct{}
+
+func (snappyCodec) ID() byte     { return CodecSnappy }
+func (snappyCodec) Name() string { return "snappy" }
+
+func (snappyCodec) NewWriter(w io.Writer, _ Level) (io.WriteCloser, error) {
+	return snappy.NewBufferedWriter(w), nil
+}
+
+func (snappyCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(snappy.NewReader(r)), nil
+}
+
+type lz4Codec struct{}
+
+func (lz4Codec) ID() byte     { return CodecLZ4 }
+func (lz4Codec) Name() string { return "lz4" }
+
+func (lz4Codec) NewWriter(w io.Writer, level Level) (io.WriteCloser, error) {
+	l := lz4.Level5
+
+	switch level {
+	case LevelFastest:
+		l = lz4.Fast
+	case LevelBest:
+		l = lz4.Level9
+	}
+
+	zw := lz4.NewWriter(w)
+	if err := zw.Apply(lz4.CompressionLevelOption(l)); err != nil {
+		return nil, err
+	}
+
+	return zw, nil
+}
+
+func (lz4Codec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(lz4.NewReader(r)), nil
+}
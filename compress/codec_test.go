@@ -0,0 +1,168 @@
+package compress
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"testing"
+
+	"github.com/go-pantheon/fabrica-util/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodecs(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte(`{"id":1,"name":"item","tags":["a","b"]}`), 1000)
+
+	tests := []struct {
+		codec Codec
+		id    byte
+	}{
+		{Zlib, CodecZlib},
+		{Gzip, CodecGzip},
+		{Zstd, CodecZstd},
+		{Snappy, CodecSnappy},
+		{LZ4, CodecLZ4},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.codec.Name(), func(t *testing.T) {
+			t.Parallel()
+
+			c, ok := Lookup(tt.id)
+			require.True(t, ok)
+			assert.Equal(t, tt.codec, c)
+
+			compressed, didCompress, err := CompressWith(tt.codec, data)
+			require.NoError(t, err)
+			require.True(t, didCompress)
+			assert.Equal(t, tt.id, compressed[0])
+			assert.Less(t, len(compressed), len(data)/5)
+
+			decompressed, err := Decompress(compressed)
+			require.NoError(t, err)
+			assert.Equal(t, data, decompressed)
+
+			for _, level := range []Level{LevelFastest, LevelDefault, LevelBest} {
+				var buf bytes.Buffer
+
+				w, err := tt.codec.NewWriter(&buf, level)
+				require.NoError(t, err)
+
+				_, err = w.Write(data)
+				require.NoError(t, err)
+				require.NoError(t, w.Close())
+
+				r, err := tt.codec.NewReader(&buf)
+				require.NoError(t, err)
+
+				got, err := io.ReadAll(r)
+				require.NoError(t, err)
+				require.NoError(t, r.Close())
+				assert.Equalf(t, data, got, "Level %d", level)
+			}
+		})
+	}
+}
+
+func TestLegacyZlib(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("legacy"), 1000)
+
+	for _, level := range []int{zlib.BestSpeed, zlib.DefaultCompression, zlib.BestCompression} {
+		var buf bytes.Buffer
+
+		w, err := zlib.NewWriterLevel(&buf, level)
+		require.NoError(t, err)
+
+		_, err = w.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		decompressed, err := Decompress(buf.Bytes())
+		require.NoError(t, err)
+		assert.Equalf(t, data, decompressed, "Level %d", level)
+	}
+}
+
+func TestDefaultIsLegacyZlib(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("rolling upgrade"), 1000)
+
+	compressed, didCompress, err := Compress(data)
+	require.NoError(t, err)
+	require.True(t, didCompress)
+
+	// processes not yet upgraded decode the default output with plain zlib
+	r, err := zlib.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+
+	decompressed, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+
+	decompressed, err = Decompress(compressed)
+	require.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+
+	// an explicit zlib codec still gets its header
+	compressed, _, err = CompressWith(Zlib, data)
+	require.NoError(t, err)
+	assert.Equal(t, CodecZlib, compressed[0])
+}
+
+func TestRegister(t *testing.T) {
+	t.Parallel()
+
+	_, err := Decompress([]byte{0x7e, 0x00})
+	assert.True(t, errors.Is(err, ErrUnknownCodec))
+
+	assert.Panics(t, func() { Register(nil) })
+	assert.Panics(t, func() { Register(Zlib) }, "duplicate ID")
+	assert.Panics(t, func() { Register(testCodec{id: 0}) })
+	assert.Panics(t, func() { Register(testCodec{id: 0x80}) })
+	assert.Panics(t, func() { Register(testCodec{id: 0x78}) }, "ID colliding with legacy zlib")
+
+	// the registry is global, -count=N runs the test again in the same process
+	if _, ok := Lookup(0x7d); !ok {
+		Register(testCodec{id: 0x7d})
+	}
+
+	c, ok := Lookup(0x7d)
+	require.True(t, ok)
+
+	compressed, didCompress, err := CompressWith(c, bytes.Repeat([]byte{1}, testWeakThreshold))
+	require.NoError(t, err)
+	require.True(t, didCompress)
+
+	decompressed, err := Decompress(compressed)
+	require.NoError(t, err)
+	assert.Equal(t, bytes.Repeat([]byte{1}, testWeakThreshold), decompressed)
+}
+
+// testCodec stores the data as is
+type testCodec struct {
+	id byte
+}
+
+func (c testCodec) ID() byte     { return c.id }
+func (c testCodec) Name() string { return "test" }
+
+func (testCodec) NewWriter(w io.Writer, _ Level) (io.WriteCloser, error) {
+	return nopWriteCloser{w}, nil
+}
+
+func (testCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(r), nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
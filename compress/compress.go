@@ -1,130 +1,82 @@
-// Package compress provides tools for compressing and decompressing data using deflate and gzip
+// Package compress provides tools for compressing and decompressing data with pluggable codecs
+// such as zlib, gzip, zstd, snappy and lz4. Compressed data starts with a one-byte codec ID, so
+// Decompress picks the right algorithm automatically. The default zlib output of Compress stays
+// headerless as in older versions, Decompress detects it.
+// The package level functions use a default Compressor, create others with NewCompressor.
 package compress
 
 import (
-	"bytes"
-	"compress/zlib"
-	"slices"
+	"context"
 	"sync"
-	"sync/atomic"
-
-	"github.com/go-pantheon/fabrica-util/errors"
 )
 
 var (
-	defaultWeakThreshold   = &atomic.Int64{}
-	defaultStrongThreshold = &atomic.Int64{}
-	defaultWeakLevel       = zlib.BestSpeed
-	defaultStrongLevel     = zlib.DefaultCompression
-)
-
-var (
-	bufferPool = sync.Pool{
-		New: func() any {
-			return new(bytes.Buffer)
-		},
-	}
-	once = sync.Once{}
+	defaultCompressor = NewCompressor()
+	once              = sync.Once{}
 )
 
-func init() {
-	defaultWeakThreshold.Store(10 << 10)    // 10KB
-	defaultStrongThreshold.Store(512 << 10) // 512KB
-}
-
-// Init init compress params
+// Init init compress params of the default compressor, only the first call applies
 // weak: weak compress threshold, compress when data length is greater than this value
 // strong: strong compress threshold, use higher compression rate when data length is greater than this value
 func Init(weak, strong int64) {
 	once.Do(func() {
-		if weak > 0 && strong > 0 && weak < strong {
-			defaultWeakThreshold.Store(weak)
-			defaultStrongThreshold.Store(strong)
-		}
+		defaultCompressor.setThresholds(weak, strong)
 	})
 }
 
-// Compress auto select compress strategy based on data length
-// return compressed data, whether compression is performed, error info
-func Compress(data []byte) (ret []byte, didCompress bool, err error) {
-	dataLen := int64(len(data))
-	if dataLen == 0 {
-		return []byte{}, false, nil
-	}
-
-	weakThreshold := defaultWeakThreshold.Load()
-	strongThreshold := defaultStrongThreshold.Load()
-
-	if dataLen < weakThreshold {
-		return data, false, nil
-	}
-
-	level := defaultWeakLevel
-	if dataLen >= strongThreshold {
-		level = defaultStrongLevel
-	}
+// Default returns the default compressor used by the package level functions
+func Default() *Compressor {
+	return defaultCompressor
+}
 
-	if level < zlib.BestSpeed || level > zlib.BestCompression {
-		level = zlib.DefaultCompression
+// SetMaxDecompressSize set the output limit of Decompress, n <= 0 restores DefaultMaxDecompressSize
+func SetMaxDecompressSize(n int64) {
+	if n <= 0 {
+		n = DefaultMaxDecompressSize
 	}
 
-	buffer := bufferPool.Get().(*bytes.Buffer)
-	defer func() {
-		buffer.Reset()
-		bufferPool.Put(buffer)
-	}()
-
-	writer, err := zlib.NewWriterLevel(buffer, level)
-	if err != nil {
-		return nil, false, errors.Wrapf(err, "create zlib writer failed (level %d)", level)
-	}
+	defaultCompressor.maxDecompressSize.Store(n)
+}
 
-	if _, err = writer.Write(data); err != nil {
-		return nil, false, errors.Wrap(err, "write to compressor failed")
-	}
+// Compress auto select compress strategy based on data length, using headerless zlib
+// return compressed data, whether compression is performed, error info
+func Compress(data []byte) (ret []byte, didCompress bool, err error) {
+	return defaultCompressor.Compress(data)
+}
 
-	if err = writer.Close(); err != nil {
-		return nil, false, errors.Wrap(err, "close compressor failed")
-	}
+// CompressWith auto select compress strategy based on data length, using the codec
+// return compressed data prefixed with the codec ID, whether compression is performed, error info
+func CompressWith(c Codec, data []byte) (ret []byte, didCompress bool, err error) {
+	return defaultCompressor.CompressWith(c, data)
+}
 
-	ret = slices.Clone(buffer.Bytes())
-	didCompress = true
+// CompressWithDict compress data with the codec and the dictionary, ignoring the weak threshold
+// The dictionary doesn't need to be registered to compress, only to decompress
+// return compressed data, whether compression is performed (false when it doesn't save space), error info
+func CompressWithDict(c Codec, d *Dict, data []byte) (ret []byte, didCompress bool, err error) {
+	return defaultCompressor.CompressWithDict(c, d, data)
+}
 
-	return ret, didCompress, err
+// CompressAdaptive compresses data only when it is likely to pay off, see Compressor.CompressAdaptive
+// return the data to send, the decision taken, error info
+func CompressAdaptive(category string, data []byte) (ret []byte, decision Decision, err error) {
+	return defaultCompressor.CompressAdaptive(category, data)
 }
 
-// Decompress decompress data
+// Decompress decompress data with the codec and the dictionary named by its header
+// Headerless zlib data produced by older versions is still accepted
+// The output is limited to the package max decompress size, see SetMaxDecompressSize
 func Decompress(data []byte) (ret []byte, err error) {
-	if len(data) == 0 {
-		return []byte{}, nil
-	}
-
-	reader, err := zlib.NewReader(bytes.NewReader(data))
-	if err != nil {
-		err = errors.Wrap(err, "create zlib reader failed")
-
-		return nil, err
-	}
-
-	buffer := bufferPool.Get().(*bytes.Buffer)
-	defer func() {
-		buffer.Reset()
-		bufferPool.Put(buffer)
-	}()
-
-	if _, err = buffer.ReadFrom(reader); err != nil {
-		err = errors.Wrap(err, "read from decompressor failed")
-
-		return nil, err
-	}
-
-	if err = reader.Close(); err != nil {
-		err = errors.Wrap(err, "close decompressor failed")
-
-		return nil, err
-	}
+	return defaultCompressor.Decompress(data)
+}
 
-	ret = slices.Clone(buffer.Bytes())
+// DecompressWithLimit decompress data, failing with a *LimitError once the output exceeds maxSize bytes
+// maxSize <= 0 uses the package max decompress size
+func DecompressWithLimit(data []byte, maxSize int64, opts ...LimitOption) (ret []byte, err error) {
+	return defaultCompressor.DecompressWithLimit(data, maxSize, opts...)
+}
 
-	return ret, nil
+// DecompressContext is DecompressWithLimit that aborts when ctx is done
+func DecompressContext(ctx context.Context, data []byte, maxSize int64, opts ...LimitOption) (ret []byte, err error) {
+	return defaultCompressor.DecompressContext(ctx, data, maxSize, opts...)
 }
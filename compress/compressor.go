@@ -0,0 +1,346 @@
+package compress
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-pantheon/fabrica-util/errors"
+)
+
+const (
+	defaultWeakThreshold   = 10 << 10  // 10KB
+	defaultStrongThreshold = 512 << 10 // 512KB
+)
+
+// BufferPool provides the buffers a Compressor works in
+type BufferPool interface {
+	Get() *bytes.Buffer
+	Put(*bytes.Buffer)
+}
+
+// Compressor compresses and decompresses data with its own thresholds, levels, codec and buffer pool
+// It is safe for concurrent use
+type Compressor struct {
+	weakThreshold     atomic.Int64
+	strongThreshold   atomic.Int64
+	maxDecompressSize atomic.Int64
+
+	weakLevel   Level
+	strongLevel Level
+	codec       Codec
+	pool        BufferPool
+
+	// adaptive compression, see CompressAdaptive
+	minGain    float64
+	maxEntropy float64
+	sampleSize int
+	categories sync.Map // category name -> *categoryStats
+
+	bytesIn    atomic.Int64
+	bytesOut   atomic.Int64
+	compressed atomic.Int64
+	skipped    atomic.Int64
+}
+
+// Option define the type of the compressor option function
+type Option func(*Compressor)
+
+// WithThresholds set the compress thresholds, 0 < weak < strong
+// weak: compress when data length is greater than this value
+// strong: use the strong level when data length is greater than this value
+func WithThresholds(weak, strong int64) Option {
+	return func(c *Compressor) {
+		c.setThresholds(weak, strong)
+	}
+}
+
+// WithLevels set the levels used below and above the strong threshold
+func WithLevels(weak, strong Level) Option {
+	return func(c *Compressor) {
+		c.weakLevel = weak
+		c.strongLevel = strong
+	}
+}
+
+// WithCodec set the codec of Compress, zlib by default
+func WithCodec(codec Codec) Option {
+	return func(c *Compressor) {
+		c.codec = codec
+	}
+}
+
+// WithBufferPool set the pool of the buffers the compressor works in
+func WithBufferPool(pool BufferPool) Option {
+	return func(c *Compressor) {
+		c.pool = pool
+	}
+}
+
+// WithMaxDecompressSize set the output limit of Decompress, DefaultMaxDecompressSize by default
+func WithMaxDecompressSize(n int64) Option {
+	return func(c *Compressor) {
+		if n > 0 {
+			c.maxDecompressSize.Store(n)
+		}
+	}
+}
+
+// NewCompressor create a compressor, the defaults are those of the package level functions
+func NewCompressor(opts ...Option) *Compressor {
+	c := &Compressor{
+		weakLevel:   LevelFastest,
+		strongLevel: LevelDefault,
+		codec:       Zlib,
+		pool:        &syncBufferPool{},
+		minGain:     DefaultMinGain,
+		maxEntropy:  DefaultMaxEntropy,
+		sampleSize:  DefaultEntropySample,
+	}
+
+	c.weakThreshold.Store(defaultWeakThreshold)
+	c.strongThreshold.Store(defaultStrongThreshold)
+	c.maxDecompressSize.Store(DefaultMaxDecompressSize)
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Compress auto select compress strategy based on data length, using the codec of the compressor
+// zlib output is headerless like older versions, so that processes not yet upgraded can read it
+// return compressed data, whether compression is performed, error info
+func (c *Compressor) Compress(data []byte) (ret []byte, didCompress bool, err error) {
+	return c.compress(c.codec, nil, data, !isZlib(c.codec))
+}
+
+// CompressWith auto select compress strategy based on data length, using the codec
+// return compressed data prefixed with the codec ID, whether compression is performed, error info
+func (c *Compressor) CompressWith(codec Codec, data []byte) (ret []byte, didCompress bool, err error) {
+	return c.compress(codec, nil, data, true)
+}
+
+// CompressWithDict compress data with the codec and the dictionary, ignoring the weak threshold
+// The dictionary doesn't need to be registered to compress, only to decompress
+// return compressed data, whether compression is performed (false when it doesn't save space), error info
+func (c *Compressor) CompressWithDict(codec Codec, d *Dict, data []byte) (ret []byte, didCompress bool, err error) {
+	return c.compress(codec, d, data, true)
+}
+
+func (c *Compressor) compress(codec Codec, d *Dict, data []byte, header bool) (ret []byte, didCompress bool, err error) {
+	dataLen := int64(len(data))
+	if dataLen == 0 {
+		return []byte{}, false, nil
+	}
+
+	// a dictionary makes messages below the weak threshold worth compressing
+	if d == nil && dataLen < c.weakThreshold.Load() {
+		c.skipped.Add(1)
+		return data, false, nil
+	}
+
+	if ret, err = c.encode(codec, d, data, header); err != nil {
+		return nil, false, err
+	}
+
+	if d != nil && len(ret) >= len(data) {
+		c.skipped.Add(1)
+		return data, false, nil
+	}
+
+	c.record(len(data), len(ret))
+
+	return ret, true, nil
+}
+
+// encode compresses data with the header of the codec and the dictionary, the level depends on the length
+// Without header the output is plain codec data, only used for legacy zlib
+func (c *Compressor) encode(codec Codec, d *Dict, data []byte, header bool) ([]byte, error) {
+	level := c.weakLevel
+	if int64(len(data)) >= c.strongThreshold.Load() {
+		level = c.strongLevel
+	}
+
+	buffer := c.pool.Get()
+	defer func() {
+		buffer.Reset()
+		c.pool.Put(buffer)
+	}()
+
+	writer, err := newWriter(buffer, codec, d, level, header)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = writer.Write(data); err != nil {
+		return nil, errors.Wrap(err, "write to compressor failed")
+	}
+
+	if err = writer.Close(); err != nil {
+		return nil, errors.Wrap(err, "close compressor failed")
+	}
+
+	return slices.Clone(buffer.Bytes()), nil
+}
+
+// record counts a compressed call in the stats
+func (c *Compressor) record(in, out int) {
+	c.compressed.Add(1)
+	c.bytesIn.Add(int64(in))
+	c.bytesOut.Add(int64(out))
+}
+
+// Decompress decompress data with the codec and the dictionary named by its header
+// Headerless zlib data produced by older versions is still accepted
+// The output is limited to the max decompress size of the compressor
+func (c *Compressor) Decompress(data []byte) (ret []byte, err error) {
+	return c.DecompressContext(context.Background(), data, 0)
+}
+
+// DecompressWithLimit decompress data, failing with a *LimitError once the output exceeds maxSize bytes
+// maxSize <= 0 uses the max decompress size of the compressor
+func (c *Compressor) DecompressWithLimit(data []byte, maxSize int64, opts ...LimitOption) (ret []byte, err error) {
+	return c.DecompressContext(context.Background(), data, maxSize, opts...)
+}
+
+// DecompressContext is DecompressWithLimit that aborts when ctx is done
+func (c *Compressor) DecompressContext(ctx context.Context, data []byte, maxSize int64, opts ...LimitOption) (ret []byte, err error) {
+	if len(data) == 0 {
+		return []byte{}, nil
+	}
+
+	if maxSize <= 0 {
+		maxSize = c.maxDecompressSize.Load()
+	}
+
+	limit := newLimit(len(data), maxSize, opts...)
+
+	codec, d, offset, err := codecOf(data)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := newReader(codec, d, bytes.NewReader(data[offset:]))
+	if err != nil {
+		err = errors.Wrapf(err, "create %s reader failed", codec.Name())
+
+		return nil, err
+	}
+
+	buffer := c.pool.Get()
+	defer func() {
+		buffer.Reset()
+		c.pool.Put(buffer)
+	}()
+
+	if err = limit.read(ctx, buffer, reader); err != nil {
+		_ = reader.Close()
+
+		return nil, err
+	}
+
+	if err = reader.Close(); err != nil {
+		err = errors.Wrap(err, "close decompressor failed")
+
+		return nil, err
+	}
+
+	ret = slices.Clone(buffer.Bytes())
+
+	return ret, nil
+}
+
+// Stats return the usage statistics of the compressor
+type Stats struct {
+	// BytesIn and BytesOut are the input and output sizes of the compressed calls
+	BytesIn  int64
+	BytesOut int64
+	// Ratio is BytesOut / BytesIn, 0 before the first compressed call
+	Ratio float64
+	// Compressed and Skipped count the calls that did and didn't compress
+	Compressed int64
+	Skipped    int64
+}
+
+// GetStats return the usage statistics of the compressor
+func (c *Compressor) GetStats() Stats {
+	stats := Stats{
+		BytesIn:    c.bytesIn.Load(),
+		BytesOut:   c.bytesOut.Load(),
+		Compressed: c.compressed.Load(),
+		Skipped:    c.skipped.Load(),
+	}
+
+	if stats.BytesIn > 0 {
+		stats.Ratio = float64(stats.BytesOut) / float64(stats.BytesIn)
+	}
+
+	return stats
+}
+
+func (c *Compressor) setThresholds(weak, strong int64) {
+	if weak > 0 && strong > 0 && weak < strong {
+		c.weakThreshold.Store(weak)
+		c.strongThreshold.Store(strong)
+	}
+}
+
+// isZlib reports whether the codec is zlib, whose data older versions decode without header
+func isZlib(codec Codec) bool {
+	return codec.ID() == CodecZlib
+}
+
+// newWriter writes the header of the codec and the dictionary into buffer and returns the codec writer
+// Dictionaries always need the header, header only applies without one
+func newWriter(buffer *bytes.Buffer, codec Codec, d *Dict, level Level, header bool) (io.WriteCloser, error) {
+	if d == nil {
+		if header {
+			buffer.WriteByte(codec.ID())
+		}
+
+		writer, err := codec.NewWriter(buffer, level)
+		if err != nil {
+			return nil, errors.Wrapf(err, "create %s writer failed (level %d)", codec.Name(), level)
+		}
+
+		return writer, nil
+	}
+
+	dc, ok := codec.(DictCodec)
+	if !ok {
+		return nil, errors.Errorf("codec %s doesn't support dictionaries", codec.Name())
+	}
+
+	buffer.WriteByte(codec.ID() | dictFlag)
+	buffer.Write(binary.BigEndian.AppendUint32(nil, d.ID))
+
+	writer, err := dc.NewDictWriter(buffer, level, d)
+	if err != nil {
+		return nil, errors.Wrapf(err, "create %s writer failed (level %d, dict %d)", codec.Name(), level, d.ID)
+	}
+
+	return writer, nil
+}
+
+// syncBufferPool is the default BufferPool
+type syncBufferPool struct {
+	pool sync.Pool
+}
+
+func (p *syncBufferPool) Get() *bytes.Buffer {
+	if buffer, ok := p.pool.Get().(*bytes.Buffer); ok {
+		return buffer
+	}
+
+	return new(bytes.Buffer)
+}
+
+func (p *syncBufferPool) Put(buffer *bytes.Buffer) {
+	p.pool.Put(buffer)
+}
@@ -0,0 +1,148 @@
+package compress
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-pantheon/fabrica-util/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressorThresholds(t *testing.T) {
+	t.Parallel()
+
+	c := NewCompressor(WithThresholds(100, 1000), WithLevels(LevelFastest, LevelBest))
+
+	tests := []struct {
+		name    string
+		dataLen int
+		want    bool
+	}{
+		{"BelowWeak", 99, false},
+		{"EqualWeak", 100, true},
+		{"AboveStrong", 1001, true},
+		{"PackageWeakThreshold", testWeakThreshold - 1, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, didCompress, err := c.Compress(make([]byte, tt.dataLen))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, didCompress)
+		})
+	}
+
+	// invalid thresholds keep the defaults
+	d := NewCompressor(WithThresholds(1000, 100))
+
+	_, didCompress, err := d.Compress(make([]byte, defaultWeakThreshold-1))
+	require.NoError(t, err)
+	assert.False(t, didCompress)
+}
+
+func TestCompressorCodec(t *testing.T) {
+	t.Parallel()
+
+	c := NewCompressor(WithCodec(LZ4), WithThresholds(1, 2))
+	data := bytes.Repeat([]byte("lz4"), 100)
+
+	compressed, didCompress, err := c.Compress(data)
+	require.NoError(t, err)
+	require.True(t, didCompress)
+	assert.Equal(t, CodecLZ4, compressed[0])
+
+	// the header names the codec, any compressor can decompress
+	decompressed, err := Decompress(compressed)
+	require.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+}
+
+func TestCompressorStats(t *testing.T) {
+	t.Parallel()
+
+	c := NewCompressor(WithThresholds(100, 1000))
+	assert.Equal(t, Stats{}, c.GetStats())
+
+	_, _, err := c.Compress(make([]byte, 10))
+	require.NoError(t, err)
+
+	compressed, _, err := c.Compress(make([]byte, 2000))
+	require.NoError(t, err)
+
+	_, _, err = c.CompressWithDict(Zstd, trainedDict, randBytes(16))
+	require.NoError(t, err)
+
+	stats := c.GetStats()
+	assert.Equal(t, int64(1), stats.Compressed)
+	assert.Equal(t, int64(2), stats.Skipped)
+	assert.Equal(t, int64(2000), stats.BytesIn)
+	assert.Equal(t, int64(len(compressed)), stats.BytesOut)
+	assert.InDelta(t, float64(len(compressed))/2000, stats.Ratio, 1e-9)
+}
+
+func TestCompressorLimits(t *testing.T) {
+	t.Parallel()
+
+	c := NewCompressor(WithThresholds(1, 2), WithMaxDecompressSize(1000))
+
+	compressed, _, err := c.Compress(make([]byte, 1001))
+	require.NoError(t, err)
+
+	_, err = c.Decompress(compressed)
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+
+	got, err := c.DecompressWithLimit(compressed, 1001)
+	require.NoError(t, err)
+	assert.Len(t, got, 1001)
+
+	got, err = Decompress(compressed)
+	require.NoError(t, err, "the default compressor has its own limit")
+	assert.Len(t, got, 1001)
+}
+
+func TestCompressorBufferPool(t *testing.T) {
+	t.Parallel()
+
+	pool := &countingPool{}
+	c := NewCompressor(WithBufferPool(pool), WithThresholds(1, 2))
+
+	compressed, _, err := c.Compress(bytes.Repeat([]byte{1}, 100))
+	require.NoError(t, err)
+
+	_, err = c.Decompress(compressed)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(2), pool.gets.Load())
+	assert.Equal(t, int64(2), pool.puts.Load())
+}
+
+func TestInitOnce(t *testing.T) {
+	t.Parallel()
+
+	// TestMain already initialized the default compressor
+	Init(testWeakThreshold*2, testStrongThreshold*2)
+
+	assert.Equal(t, int64(testWeakThreshold), Default().weakThreshold.Load())
+	assert.Equal(t, int64(testStrongThreshold), Default().strongThreshold.Load())
+}
+
+type countingPool struct {
+	syncBufferPool
+	gets atomic.Int64
+	puts atomic.Int64
+}
+
+func (p *countingPool) Get() *bytes.Buffer {
+	p.gets.Add(1)
+	return p.syncBufferPool.Get()
+}
+
+func (p *countingPool) Put(buffer *bytes.Buffer) {
+	p.puts.Add(1)
+	p.syncBufferPool.Put(buffer)
+}
@@ -0,0 +1,185 @@
+package compress
+
+import (
+	"cmp"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-pantheon/fabrica-util/errors"
+)
+
+// Compressed data using a dictionary has the dictFlag bit set in its header byte, followed by
+// the big-endian uint32 dictionary ID
+const (
+	dictFlag   = 0x80
+	dictIDSize = 4
+
+	// MaxDictSize is the largest useful dictionary, deflate only looks 32KB back
+	MaxDictSize = 32 << 10
+
+	// dictGramSize is the length of the substrings TrainDict counts across samples
+	dictGramSize = 8
+)
+
+// ErrUnknownDict is returned when the header of the compressed data names no registered dictionary
+var ErrUnknownDict = errors.New("compress: unknown dictionary")
+
+// Dict is a preset dictionary, the compressing and the decompressing side must register the same one
+// Small messages share most of their content with the dictionary, so they compress well on their own
+type Dict struct {
+	ID   uint32
+	Data []byte
+}
+
+// DictCodec is implemented by codecs supporting preset dictionaries, zlib and zstd do
+type DictCodec interface {
+	Codec
+	NewDictWriter(w io.Writer, level Level, d *Dict) (io.WriteCloser, error)
+	NewDictReader(r io.Reader, d *Dict) (io.ReadCloser, error)
+}
+
+var (
+	dictsMu sync.RWMutex
+	dicts   = map[uint32]*Dict{}
+)
+
+// RegisterDict makes a dictionary available to Decompress by its ID
+// It panics if the ID is 0 or already registered
+func RegisterDict(d *Dict) {
+	if d == nil || d.ID == 0 {
+		panic("compress: RegisterDict dictionary is nil or has ID 0")
+	}
+
+	dictsMu.Lock()
+	defer dictsMu.Unlock()
+
+	if _, ok := dicts[d.ID]; ok {
+		panic("compress: RegisterDict called twice for dictionary " + strconv.FormatUint(uint64(d.ID), 10))
+	}
+
+	dicts[d.ID] = d
+}
+
+// LookupDict returns the dictionary registered with the ID
+func LookupDict(id uint32) (*Dict, bool) {
+	dictsMu.RLock()
+	defer dictsMu.RUnlock()
+
+	d, ok := dicts[id]
+
+	return d, ok
+}
+
+// newReader returns the reader of the codec, using the dictionary if there is one
+func newReader(c Codec, d *Dict, r io.Reader) (io.ReadCloser, error) {
+	if d == nil {
+		return c.NewReader(r)
+	}
+
+	dc, ok := c.(DictCodec)
+	if !ok {
+		return nil, errors.Errorf("codec %s doesn't support dictionaries", c.Name())
+	}
+
+	return dc.NewDictReader(r, d)
+}
+
+// TrainDict builds a raw content dictionary of at most size bytes from sample payloads
+// It keeps the longest substrings shared by several samples, the most valuable ones at the end
+// where deflate and zstd reach them with the shortest distances. A few hundred samples of the
+// same message type give a good dictionary, size <= 0 means MaxDictSize.
+func TrainDict(id uint32, samples [][]byte, size int) *Dict {
+	if size <= 0 || size > MaxDictSize {
+		size = MaxDictSize
+	}
+
+	// document frequency of every gram, a gram repeated inside one sample counts once
+	grams := make(map[string]int)
+	seen := make(map[string]struct{})
+
+	for _, s := range samples {
+		clear(seen)
+
+		for i := 0; i+dictGramSize <= len(s); i++ {
+			g := string(s[i : i+dictGramSize])
+			if _, ok := seen[g]; !ok {
+				seen[g] = struct{}{}
+				grams[g]++
+			}
+		}
+	}
+
+	// maximal runs of overlapping shared grams form the candidate segments
+	segments := make(map[string]int)
+
+	for _, s := range samples {
+		clear(seen)
+
+		for i := 0; i+dictGramSize <= len(s); {
+			if grams[string(s[i:i+dictGramSize])] < 2 {
+				i++
+				continue
+			}
+
+			j := i + 1
+			for j+dictGramSize <= len(s) && grams[string(s[j:j+dictGramSize])] >= 2 {
+				j++
+			}
+
+			seg := string(s[i : j-1+dictGramSize])
+			if _, ok := seen[seg]; !ok {
+				seen[seg] = struct{}{}
+				segments[seg]++
+			}
+
+			i = j
+		}
+	}
+
+	type candidate struct {
+		seg   string
+		score int
+	}
+
+	candidates := make([]candidate, 0, len(segments))
+	for seg, count := range segments {
+		candidates = append(candidates, candidate{seg: seg, score: count * len(seg)})
+	}
+
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		if c := cmp.Compare(b.score, a.score); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.seg, b.seg)
+	})
+
+	picked := make([]string, 0)
+	total := 0
+
+	for _, c := range candidates {
+		if total+len(c.seg) > size {
+			continue
+		}
+
+		if slices.ContainsFunc(picked, func(p string) bool { return strings.Contains(p, c.seg) }) {
+			continue
+		}
+
+		picked = append(picked, c.seg)
+		total += len(c.seg)
+	}
+
+	data := make([]byte, 0, total)
+	for _, seg := range slices.Backward(picked) {
+		data = append(data, seg...)
+	}
+
+	return &Dict{
+		ID:   id,
+		Data: data,
+	}
+}
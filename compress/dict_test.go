@@ -0,0 +1,145 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/go-pantheon/fabrica-util/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// trainedDict is shared by the tests, the dictionary registry is global
+var trainedDict = func() *Dict {
+	samples := make([][]byte, 0, 200)
+	for i := range 200 {
+		samples = append(samples, protoMessage(i))
+	}
+
+	d := TrainDict(1001, samples, 4<<10)
+	RegisterDict(d)
+
+	return d
+}()
+
+func TestCompressWithDict(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range []Codec{Zlib, Zstd} {
+		c := c
+		t.Run(c.Name(), func(t *testing.T) {
+			t.Parallel()
+
+			var plainSize, dictSize int
+
+			for i := 1000; i < 1100; i++ {
+				msg := protoMessage(i)
+				require.Less(t, len(msg), testWeakThreshold, "messages must be below the weak threshold")
+
+				compressed, didCompress, err := CompressWithDict(c, trainedDict, msg)
+				require.NoError(t, err)
+				require.True(t, didCompress)
+				assert.Equal(t, c.ID()|dictFlag, compressed[0])
+				assert.Equal(t, trainedDict.ID, binary.BigEndian.Uint32(compressed[1:]))
+
+				decompressed, err := Decompress(compressed)
+				require.NoError(t, err)
+				assert.Equal(t, msg, decompressed)
+
+				dictSize += len(compressed)
+				plainSize += len(compress(t, c, msg))
+			}
+
+			t.Logf("%s without dictionary %d bytes, with dictionary %d bytes", c.Name(), plainSize, dictSize)
+			assert.Less(t, dictSize, plainSize*2/3)
+		})
+	}
+}
+
+func TestDictErrors(t *testing.T) {
+	t.Parallel()
+
+	msg := protoMessage(1)
+
+	_, _, err := CompressWithDict(Snappy, trainedDict, msg)
+	assert.Error(t, err, "snappy has no dictionaries")
+
+	unregistered := &Dict{ID: 1002, Data: trainedDict.Data}
+
+	compressed, didCompress, err := CompressWithDict(Zlib, unregistered, msg)
+	require.NoError(t, err)
+	require.True(t, didCompress)
+
+	_, err = Decompress(compressed)
+	assert.True(t, errors.Is(err, ErrUnknownDict))
+
+	_, err = Decompress(compressed[:3])
+	assert.True(t, errors.Is(err, ErrUnknownDict))
+
+	// incompressible data is returned as is
+	random := randBytes(64)
+
+	ret, didCompress, err := CompressWithDict(Zstd, trainedDict, random)
+	require.NoError(t, err)
+	assert.False(t, didCompress)
+	assert.Equal(t, random, ret)
+
+	assert.Panics(t, func() { RegisterDict(trainedDict) })
+	assert.Panics(t, func() { RegisterDict(&Dict{}) })
+}
+
+func TestTrainDict(t *testing.T) {
+	t.Parallel()
+
+	samples := [][]byte{
+		[]byte(`{"player_id":1,"scene":"lobby"}`),
+		[]byte(`{"player_id":2,"scene":"arena"}`),
+		[]byte(`{"player_id":3,"scene":"lobby"}`),
+	}
+
+	d := TrainDict(7, samples, 0)
+	assert.Equal(t, uint32(7), d.ID)
+	assert.Contains(t, string(d.Data), `{"player_id":`)
+	assert.Contains(t, string(d.Data), `,"scene":"lobby"}`)
+
+	assert.LessOrEqual(t, len(TrainDict(8, samples, 16).Data), 16)
+	assert.Empty(t, TrainDict(9, nil, 0).Data)
+}
+
+// protoMessage returns a small JSON message like the ones of the game protocol
+func protoMessage(i int) []byte {
+	msg := map[string]any{
+		"player_id": 100000 + i,
+		"name":      fmt.Sprintf("player-%d", i),
+		"scene":     []string{"lobby", "arena", "dungeon"}[i%3],
+		"position":  map[string]int{"x": i * 7 % 1000, "y": i * 13 % 1000, "z": 0},
+		"equipment": []string{"sword_of_light", "shield_of_dawn", "boots_of_speed"},
+		"buffs":     []map[string]any{{"id": 1001, "stack": i % 5, "expire_at": 1700000000 + i}},
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		panic(err)
+	}
+
+	return data
+}
+
+// compress compresses data with the codec without header or threshold
+func compress(t *testing.T, c Codec, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	w, err := c.NewWriter(&buf, LevelFastest)
+	require.NoError(t, err)
+
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
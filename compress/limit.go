@@ -0,0 +1,116 @@
+package compress
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/go-pantheon/fabrica-util/errors"
+)
+
+// DefaultMaxDecompressSize is the default output limit of Decompress
+const DefaultMaxDecompressSize = 64 << 20 // 64MB
+
+// ErrLimitExceeded is matched by every *LimitError with errors.Is
+var ErrLimitExceeded = errors.New("compress: decompressed size limit exceeded")
+
+// LimitError is returned when the decompressed data grows over a limit, which usually means
+// a corrupted or malicious payload (decompression bomb)
+type LimitError struct {
+	// Limit is the effective output limit in bytes
+	Limit int64
+	// MaxRatio is the expansion ratio guard that set Limit, 0 if Limit came from the max size
+	MaxRatio float64
+}
+
+func (e *LimitError) Error() string {
+	if e.MaxRatio > 0 {
+		return fmt.Sprintf("%v: limit=%d (ratio %.1f)", ErrLimitExceeded, e.Limit, e.MaxRatio)
+	}
+
+	return fmt.Sprintf("%v: limit=%d", ErrLimitExceeded, e.Limit)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// LimitOption define the type of the decompression limit option function
+type LimitOption func(*limit)
+
+// WithMaxRatio limit the output to ratio times the compressed input size
+// The smaller of this and the max size applies, ratio <= 0 disables the guard
+func WithMaxRatio(ratio float64) LimitOption {
+	return func(l *limit) {
+		if ratio <= 0 {
+			return
+		}
+
+		if byRatio := float64(l.inputSize) * ratio; byRatio < float64(l.size) {
+			l.size = int64(math.Max(byRatio, 1))
+			l.ratio = ratio
+		}
+	}
+}
+
+// limit is the effective output limit of one decompression
+type limit struct {
+	inputSize int
+	size      int64
+	ratio     float64
+}
+
+func newLimit(inputSize int, maxSize int64, opts ...LimitOption) *limit {
+	l := &limit{
+		inputSize: inputSize,
+		size:      maxSize,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// read reads r into buffer, it stops as soon as the output passes the limit or ctx is done
+func (l *limit) read(ctx context.Context, buffer *bytes.Buffer, r io.Reader) error {
+	if ctx.Done() != nil {
+		r = &ctxReader{ctx: ctx, r: r}
+	}
+
+	// one extra byte tells reaching the limit from passing it
+	if l.size < math.MaxInt64 {
+		r = io.LimitReader(r, l.size+1)
+	}
+
+	if _, err := buffer.ReadFrom(r); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return errors.Wrap(ctxErr, "decompress canceled")
+		}
+
+		return errors.Wrap(err, "read from decompressor failed")
+	}
+
+	if int64(buffer.Len()) > l.size {
+		return &LimitError{Limit: l.size, MaxRatio: l.ratio}
+	}
+
+	return nil
+}
+
+// ctxReader fails reads once ctx is done
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.r.Read(p)
+}
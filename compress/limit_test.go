@@ -0,0 +1,92 @@
+package compress
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+
+	"github.com/go-pantheon/fabrica-util/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecompressWithLimit(t *testing.T) {
+	t.Parallel()
+
+	// 1MB of zeros compresses about 1000x, a small bomb
+	data := make([]byte, 1<<20)
+
+	compressed, didCompress, err := CompressWith(Zstd, data)
+	require.NoError(t, err)
+	require.True(t, didCompress)
+
+	tests := []struct {
+		name    string
+		maxSize int64
+		opts    []LimitOption
+		limit   int64
+		ratio   float64
+	}{
+		{"Exact", 1 << 20, nil, 0, 0},
+		{"Unlimited", math.MaxInt64, nil, 0, 0},
+		{"LooseRatio", 1 << 20, []LimitOption{WithMaxRatio(1e6)}, 0, 0},
+		{"Size", 1<<20 - 1, nil, 1<<20 - 1, 0},
+		{"Ratio", 1 << 30, []LimitOption{WithMaxRatio(10)}, int64(len(compressed)) * 10, 10},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := DecompressWithLimit(compressed, tt.maxSize, tt.opts...)
+			if tt.limit == 0 {
+				require.NoError(t, err)
+				assert.Equal(t, data, got)
+
+				return
+			}
+
+			assert.True(t, errors.Is(err, ErrLimitExceeded))
+
+			var limitErr *LimitError
+			require.True(t, errors.As(err, &limitErr))
+			assert.Equal(t, tt.limit, limitErr.Limit)
+			assert.Equal(t, tt.ratio, limitErr.MaxRatio)
+		})
+	}
+}
+
+func TestDecompressDefaultLimit(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte{1}, 100<<10)
+
+	compressed, _, err := Compress(data)
+	require.NoError(t, err)
+
+	got, err := DecompressWithLimit(compressed, 0)
+	require.NoError(t, err, "0 uses the package limit")
+	assert.Equal(t, data, got)
+
+	_, err = DecompressWithLimit(compressed, 100<<10-1)
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+}
+
+func TestDecompressContext(t *testing.T) {
+	t.Parallel()
+
+	compressed, _, err := Compress(bytes.Repeat([]byte{1}, 1<<20))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = DecompressContext(ctx, compressed, 0)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	got, err := DecompressContext(context.Background(), compressed, 0)
+	require.NoError(t, err)
+	assert.Len(t, got, 1<<20)
+}
@@ -0,0 +1,303 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/go-pantheon/fabrica-util/errors"
+)
+
+// Stream layout written by Writer and read by Reader, all header fields are big-endian:
+//
+//	codec    uint8 codec ID
+//	frames   repeated chunk frames
+//	  raw    uint32 uncompressed chunk length
+//	  size   uint32 compressed chunk length
+//	  data   []byte chunk compressed on its own
+//	end      a frame with both lengths 0
+//
+// Every chunk is compressed independently, so both sides only hold one chunk in memory.
+const (
+	// DefaultChunkSize is the default uncompressed size of a stream chunk
+	DefaultChunkSize = 256 << 10
+	// MaxChunkSize is the largest uncompressed size of a stream chunk
+	MaxChunkSize = 16 << 20
+
+	frameHeaderSize = 8
+)
+
+// ErrInvalidFrame is returned when a stream is malformed or truncated
+var ErrInvalidFrame = errors.New("compress: invalid stream frame")
+
+// writerResetter is implemented by codec writers that can be reused for the next chunk
+type writerResetter interface {
+	Reset(w io.Writer)
+}
+
+// Writer compresses a stream chunk by chunk into the underlying writer
+type Writer struct {
+	dst   io.Writer
+	codec Codec
+	level Level
+
+	raw       []byte
+	buf       bytes.Buffer
+	cw        io.WriteCloser
+	started   bool
+	closed    bool
+	chunkSize int
+}
+
+// WriterOption define the type of the stream writer option function
+type WriterOption func(*Writer)
+
+// WithStreamCodec set the codec of the stream, zlib by default
+func WithStreamCodec(c Codec) WriterOption {
+	return func(w *Writer) {
+		w.codec = c
+	}
+}
+
+// WithStreamLevel set the compression level of the stream
+func WithStreamLevel(level Level) WriterOption {
+	return func(w *Writer) {
+		w.level = level
+	}
+}
+
+// WithChunkSize set the uncompressed chunk size, between 1 and MaxChunkSize
+// Larger chunks compress better and hold more memory
+func WithChunkSize(size int) WriterOption {
+	return func(w *Writer) {
+		if size > 0 && size <= MaxChunkSize {
+			w.chunkSize = size
+		}
+	}
+}
+
+// NewWriter create a stream writer compressing into dst
+// Close must be called to write the end of the stream, it doesn't close dst
+func NewWriter(dst io.Writer, opts ...WriterOption) *Writer {
+	w := &Writer{
+		dst:       dst,
+		codec:     Zlib,
+		level:     LevelDefault,
+		chunkSize: DefaultChunkSize,
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+// Write implements io.Writer, a chunk is compressed and written out every time it fills up
+func (w *Writer) Write(p []byte) (n int, err error) {
+	if w.closed {
+		return 0, errClosed
+	}
+
+	if w.raw == nil {
+		w.raw = make([]byte, 0, w.chunkSize)
+	}
+
+	for len(p) > 0 {
+		c := copy(w.raw[len(w.raw):w.chunkSize], p)
+		w.raw = w.raw[:len(w.raw)+c]
+		p = p[c:]
+		n += c
+
+		if len(w.raw) == w.chunkSize {
+			if err = w.Flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// Flush compresses and writes out the buffered data as a chunk, even if it is not full
+func (w *Writer) Flush() error {
+	if len(w.raw) == 0 {
+		return nil
+	}
+
+	w.buf.Reset()
+
+	if rw, ok := w.cw.(writerResetter); ok {
+		rw.Reset(&w.buf)
+	} else {
+		cw, err := w.codec.NewWriter(&w.buf, w.level)
+		if err != nil {
+			return errors.Wrapf(err, "create %s writer failed (level %d)", w.codec.Name(), w.level)
+		}
+
+		w.cw = cw
+	}
+
+	if _, err := w.cw.Write(w.raw); err != nil {
+		return errors.Wrap(err, "write to compressor failed")
+	}
+
+	if err := w.cw.Close(); err != nil {
+		return errors.Wrap(err, "close compressor failed")
+	}
+
+	if err := w.writeFrame(uint32(len(w.raw)), w.buf.Bytes()); err != nil {
+		return err
+	}
+
+	w.raw = w.raw[:0]
+
+	return nil
+}
+
+// Close flushes the buffered data and writes the end of the stream
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	w.closed = true
+
+	return w.writeFrame(0, nil)
+}
+
+func (w *Writer) writeFrame(raw uint32, data []byte) error {
+	header := make([]byte, 0, frameHeaderSize+1)
+
+	if !w.started {
+		header = append(header, w.codec.ID())
+		w.started = true
+	}
+
+	header = binary.BigEndian.AppendUint32(header, raw)
+	header = binary.BigEndian.AppendUint32(header, uint32(len(data)))
+
+	if _, err := w.dst.Write(header); err != nil {
+		return errors.Wrap(err, "write frame header failed")
+	}
+
+	if _, err := w.dst.Write(data); err != nil {
+		return errors.Wrap(err, "write frame failed")
+	}
+
+	return nil
+}
+
+// Reader decompresses a stream written by Writer chunk by chunk
+type Reader struct {
+	src   io.Reader
+	codec Codec
+
+	chunk  []byte
+	offset int
+	header [frameHeaderSize]byte
+	err    error
+}
+
+// NewReader create a stream reader decompressing from src, the codec is read from the stream
+func NewReader(src io.Reader) (*Reader, error) {
+	var id [1]byte
+	if _, err := io.ReadFull(src, id[:]); err != nil {
+		return nil, errors.Wrapf(ErrInvalidFrame, "read codec failed: %v", err)
+	}
+
+	c, ok := Lookup(id[0])
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownCodec, "id=%d", id[0])
+	}
+
+	return &Reader{
+		src:   src,
+		codec: c,
+	}, nil
+}
+
+// Read implements io.Reader, it returns io.EOF only after the end of the stream
+// A stream cut before its end gives ErrInvalidFrame
+func (r *Reader) Read(p []byte) (int, error) {
+	for r.offset == len(r.chunk) {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		r.err = r.next()
+	}
+
+	n := copy(p, r.chunk[r.offset:])
+	r.offset += n
+
+	return n, nil
+}
+
+// next reads and decompresses the next chunk
+func (r *Reader) next() error {
+	if _, err := io.ReadFull(r.src, r.header[:]); err != nil {
+		return errors.Wrapf(ErrInvalidFrame, "read frame header failed: %v", err)
+	}
+
+	raw := binary.BigEndian.Uint32(r.header[:4])
+	size := binary.BigEndian.Uint32(r.header[4:])
+
+	if raw == 0 {
+		if size != 0 {
+			return errors.Wrapf(ErrInvalidFrame, "end frame has size %d", size)
+		}
+
+		return io.EOF
+	}
+
+	if raw > MaxChunkSize || size > 2*MaxChunkSize {
+		return errors.Wrapf(ErrInvalidFrame, "chunk too large raw=%d size=%d", raw, size)
+	}
+
+	lr := &io.LimitedReader{R: r.src, N: int64(size)}
+
+	cr, err := r.codec.NewReader(lr)
+	if err != nil {
+		return errors.Wrapf(ErrInvalidFrame, "create %s reader failed: %v", r.codec.Name(), err)
+	}
+
+	defer cr.Close()
+
+	if cap(r.chunk) < int(raw) {
+		r.chunk = make([]byte, raw)
+	}
+
+	r.chunk, r.offset = r.chunk[:raw], 0
+
+	if _, err = io.ReadFull(cr, r.chunk); err != nil {
+		r.chunk = r.chunk[:0]
+		return errors.Wrapf(ErrInvalidFrame, "decompress chunk failed: %v", err)
+	}
+
+	// the chunk must hold exactly raw bytes, then skip what the codec left unread
+	if n, _ := cr.Read(r.header[:1]); n != 0 {
+		r.chunk = r.chunk[:0]
+		return errors.Wrapf(ErrInvalidFrame, "chunk longer than %d bytes", raw)
+	}
+
+	if _, err = io.Copy(io.Discard, lr); err != nil || lr.N != 0 {
+		r.chunk = r.chunk[:0]
+		return errors.Wrapf(ErrInvalidFrame, "chunk truncated: %v", err)
+	}
+
+	return nil
+}
+
+// Close releases the reader, it doesn't close src
+func (r *Reader) Close() error {
+	r.chunk = nil
+	r.offset = 0
+	r.err = errClosed
+
+	return nil
+}
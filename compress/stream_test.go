@@ -0,0 +1,188 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/go-pantheon/fabrica-util/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStream(t *testing.T) {
+	t.Parallel()
+
+	data := append(bytes.Repeat([]byte("replay frame "), 20000), randBytes(10000)...)
+
+	for _, c := range []Codec{Zlib, Gzip, Zstd, Snappy, LZ4} {
+		c := c
+		t.Run(c.Name(), func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			w := NewWriter(&buf, WithStreamCodec(c), WithStreamLevel(LevelFastest), WithChunkSize(16<<10))
+
+			// odd sized writes cross chunk boundaries
+			for rest := data; len(rest) > 0; {
+				n := min(len(rest), 7777)
+
+				written, err := w.Write(rest[:n])
+				require.NoError(t, err)
+				require.Equal(t, n, written)
+
+				rest = rest[n:]
+			}
+
+			require.NoError(t, w.Close())
+			require.NoError(t, w.Close(), "second close is a no-op")
+			assert.Less(t, buf.Len(), len(data)/2)
+			assert.Equal(t, c.ID(), buf.Bytes()[0])
+
+			r, err := NewReader(iotest.HalfReader(&buf))
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, data, got)
+			require.NoError(t, r.Close())
+		})
+	}
+}
+
+func TestStreamChunks(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	w := NewWriter(&buf, WithChunkSize(1000))
+
+	_, err := w.Write(bytes.Repeat([]byte{'a'}, 2500))
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+
+	_, err = w.Write([]byte("tail"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	_, err = w.Write([]byte("closed"))
+	assert.Error(t, err)
+
+	// walk the frames, no chunk may exceed the chunk size
+	stream := buf.Bytes()[1:]
+	raws := make([]uint32, 0)
+
+	for len(stream) > 0 {
+		raw := binary.BigEndian.Uint32(stream)
+		size := binary.BigEndian.Uint32(stream[4:])
+		raws = append(raws, raw)
+		stream = stream[frameHeaderSize+size:]
+	}
+
+	assert.Equal(t, []uint32{1000, 1000, 500, 4, 0}, raws)
+}
+
+func TestStreamEmpty(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	require.NoError(t, NewWriter(&buf).Close())
+
+	r, err := NewReader(&buf)
+	require.NoError(t, err)
+
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestStreamReadAfterClose(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	w := NewWriter(&buf, WithChunkSize(100))
+	_, err := w.Write(bytes.Repeat([]byte("abc"), 100))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	r, err := NewReader(&buf)
+	require.NoError(t, err)
+
+	// stop in the middle of the first chunk
+	p := make([]byte, 10)
+	n, err := r.Read(p)
+	require.NoError(t, err)
+	require.Equal(t, 10, n)
+	require.NoError(t, r.Close())
+
+	n, err = r.Read(p)
+	assert.Zero(t, n)
+	assert.True(t, errors.Is(err, errClosed))
+}
+
+func TestStreamInvalid(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	w := NewWriter(&buf, WithStreamCodec(Zstd), WithChunkSize(100))
+	_, err := w.Write(bytes.Repeat([]byte("abc"), 1000))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	stream := buf.Bytes()
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"Truncated", stream[:len(stream)-frameHeaderSize], ErrInvalidFrame},
+		{"CutInChunk", stream[:20], ErrInvalidFrame},
+		{"Oversized", append([]byte{CodecZlib}, 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 1), ErrInvalidFrame},
+		{"BadEnd", append([]byte{CodecZlib}, 0, 0, 0, 0, 0, 0, 0, 1), ErrInvalidFrame},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := NewReader(bytes.NewReader(tt.data))
+			require.NoError(t, err)
+
+			_, err = io.ReadAll(r)
+			assert.True(t, errors.Is(err, tt.err), "got %v", err)
+		})
+	}
+
+	_, err = NewReader(bytes.NewReader(nil))
+	assert.True(t, errors.Is(err, ErrInvalidFrame))
+
+	_, err = NewReader(bytes.NewReader([]byte{0x7e}))
+	assert.True(t, errors.Is(err, ErrUnknownCodec))
+}
+
+func BenchmarkStream(b *testing.B) {
+	data := bytes.Repeat([]byte("replay frame "), 1<<17)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+
+		w := NewWriter(&buf)
+		_, _ = w.Write(data)
+		_ = w.Close()
+
+		r, _ := NewReader(&buf)
+		_, _ = io.Copy(io.Discard, r)
+	}
+}
@@ -0,0 +1,21 @@
+package consistenthash
+
+// Balancer maps keys of type K to nodes
+// Ring, Jump, Rendezvous and Maglev implement it, with different tradeoffs:
+// Ring supports weights, replicas and bounded loads, Jump needs no memory per node but only removes
+// nodes cheaply from the end, Rendezvous suits small weighted node sets, and Maglev looks keys up in O(1)
+type Balancer[K any] interface {
+	// AddNode adds a node
+	AddNode(nodeName string) error
+	// RemoveNode removes a node, unknown nodes are ignored
+	RemoveNode(nodeName string)
+	// GetNode returns the node of the key, false if there are no nodes
+	GetNode(key K) (string, bool)
+}
+
+var (
+	_ Balancer[string] = (*Ring[string])(nil)
+	_ Balancer[string] = (*Jump[string])(nil)
+	_ Balancer[string] = (*Rendezvous[string])(nil)
+	_ Balancer[string] = (*Maglev[string])(nil)
+)
@@ -0,0 +1,85 @@
+package consistenthash
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalancers(t *testing.T) {
+	t.Parallel()
+
+	const keys = 30_000
+
+	tests := []struct {
+		name     string
+		balancer func() Balancer[string]
+		minimal  bool
+	}{
+		{"ring", func() Balancer[string] { return NewRing(DefaultVirtualSpots) }, true},
+		{"jump", func() Balancer[string] { return NewJump(StringHasher(XXHash)) }, true},
+		{"rendezvous", func() Balancer[string] { return NewRendezvous(StringHasher(XXHash)) }, true},
+		{"maglev", func() Balancer[string] { return NewMaglev(0, StringHasher(XXHash)) }, false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := tt.balancer()
+
+			_, ok := b.GetNode("key")
+			assert.False(t, ok)
+
+			nodes := []string{"node1", "node2", "node3", "node4"}
+			for _, n := range nodes {
+				require.NoError(t, b.AddNode(n))
+			}
+
+			before := make([]string, keys)
+			distribution := make(map[string]int)
+
+			for i := range keys {
+				node, ok := b.GetNode("player:" + strconv.Itoa(i))
+				require.True(t, ok)
+
+				before[i] = node
+				distribution[node]++
+			}
+
+			for _, n := range nodes {
+				assert.InDelta(t, keys/len(nodes), distribution[n], float64(keys/len(nodes)/4), n)
+			}
+
+			// removing the last node moves its own keys, and a few others with Maglev
+			b.RemoveNode("node4")
+			b.RemoveNode("unknown")
+
+			moved := 0
+
+			for i := range keys {
+				node, ok := b.GetNode("player:" + strconv.Itoa(i))
+				require.True(t, ok)
+				assert.NotEqual(t, "node4", node)
+
+				if node != before[i] {
+					moved++
+
+					if tt.minimal {
+						assert.Equal(t, "node4", before[i])
+					}
+				}
+			}
+
+			if tt.minimal {
+				assert.Equal(t, distribution["node4"], moved)
+			} else {
+				assert.Less(t, float64(moved), 1.2*float64(distribution["node4"]))
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package consistenthash
+
+import (
+	"cmp"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/go-pantheon/fabrica-util/errors"
+)
+
+// Change is a membership change of a Ring, the removals apply before the additions
+type Change struct {
+	// Add maps the nodes to add to their weight, adding an existing node adds to its weight and
+	// places its extra virtual nodes, unless it is removed in the same change
+	Add map[string]int
+	// Remove lists the nodes to remove
+	Remove []string
+}
+
+// Range is the inclusive range [Start, End] of key hashes that move from one node to another
+// From is "" when the ring had no nodes, To is "" when it has none left
+type Range struct {
+	Start uint64
+	End   uint64
+	From  string
+	To    string
+}
+
+// Contains reports whether the key hash, see Ring.Hash, is in the range
+func (r Range) Contains(hash uint64) bool {
+	return r.Start <= hash && hash <= r.End
+}
+
+// Event describes a membership change applied to a Ring
+type Event struct {
+	Change Change
+	// Ranges are the ranges of key hashes that changed owner, in hash order
+	Ranges []Range
+}
+
+// Hash returns the hash of the key, its position on the ring
+func (h *Ring[K]) Hash(key K) uint64 {
+	return h.hasher.HashKey(key)
+}
+
+// Diff returns the ranges of key hashes that would change owner if the change was applied
+// Loads of WithBoundedLoad are ignored, the ranges follow the owner GetNodes returns first
+func (h *Ring[K]) Diff(change Change) ([]Range, error) {
+	s := h.state.Load()
+
+	// only the virtual nodes, applying the loads would remove the shared counters of the live ring
+	nodes, err := h.applyNodes(s, change)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffRanges(s.nodes, nodes), nil
+}
+
+// Apply applies the change at once and notifies the subscribers
+// Lookups keep using the previous snapshot until the next one is complete
+func (h *Ring[K]) Apply(change Change) error {
+	h.changeMu.Lock()
+	defer h.changeMu.Unlock()
+
+	prev := h.state.Load()
+
+	nodes, err := h.applyNodes(prev, change)
+	if err != nil {
+		return err
+	}
+
+	next := &ringState{
+		nodes: nodes,
+		loads: prev.loads.apply(change),
+	}
+
+	h.state.Store(next)
+
+	h.subMu.Lock()
+	subscribers := slices.Collect(maps.Values(h.subscribers))
+	h.subMu.Unlock()
+
+	if len(subscribers) == 0 {
+		return nil
+	}
+
+	event := Event{
+		Change: change,
+		Ranges: diffRanges(prev.nodes, next.nodes),
+	}
+
+	// changes such as removing an unknown node move nothing and aren't notified
+	if len(event.Ranges) == 0 {
+		return nil
+	}
+
+	for _, fn := range subscribers {
+		fn(event)
+	}
+
+	return nil
+}
+
+// Subscribe calls fn after every membership change, such as AddNode or RemoveNode, to hand off the
+// state of the keys in the moved ranges. Changes and their notifications are serialized, fn runs
+// in the goroutine that changed the ring and must not change it. The returned func unsubscribes.
+func (h *Ring[K]) Subscribe(fn func(Event)) (unsubscribe func()) {
+	h.subMu.Lock()
+	defer h.subMu.Unlock()
+
+	if h.subscribers == nil {
+		h.subscribers = make(map[uint64]func(Event))
+	}
+
+	id := h.nextID
+	h.nextID++
+	h.subscribers[id] = fn
+
+	return func() {
+		h.subMu.Lock()
+		defer h.subMu.Unlock()
+
+		delete(h.subscribers, id)
+	}
+}
+
+// applyNodes returns the sorted virtual nodes of the snapshot with the change applied, s is left as is
+func (h *Ring[K]) applyNodes(s *ringState, change Change) ([]ringNode, error) {
+	spots := 0
+
+	for name, weight := range change.Add {
+		if weight < 1 {
+			return nil, errors.Wrapf(ErrInvalidWeight, "node=%s weight=%d", name, weight)
+		}
+
+		spots += h.virtualSpots * weight
+	}
+
+	nodes := make([]ringNode, 0, len(s.nodes)+spots)
+
+	for _, n := range s.nodes {
+		if !slices.Contains(change.Remove, n.nodeName) {
+			nodes = append(nodes, n)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(change.Add)) {
+		// an existing node gets the next spots, so that adding it twice places it like weight 2
+		first := 0
+		if !slices.Contains(change.Remove, name) {
+			first = h.virtualSpots * s.loads.weights[name]
+		}
+
+		for i := first; i < first+h.virtualSpots*change.Add[name]; i++ {
+			nodes = append(nodes, ringNode{
+				nodeName: name,
+				hash:     h.hasher.HashNode(name + ":" + strconv.Itoa(i)),
+			})
+		}
+	}
+
+	slices.SortFunc(nodes, func(a, b ringNode) int {
+		return cmp.Compare(a.hash, b.hash)
+	})
+
+	return nodes, nil
+}
+
+// diffRanges returns the ranges whose owner differs between the two sorted virtual node lists
+// Ownership only changes at virtual node hashes, so comparing the owners of every hash of both
+// lists covers all of the ring
+func diffRanges(prev, next []ringNode) []Range {
+	points := make([]uint64, 0, len(prev)+len(next)+1)
+
+	for _, n := range prev {
+		points = append(points, n.hash)
+	}
+
+	for _, n := range next {
+		points = append(points, n.hash)
+	}
+
+	points = append(points, math.MaxUint64)
+	slices.Sort(points)
+	points = slices.Compact(points)
+
+	var (
+		ret   []Range
+		start uint64
+	)
+
+	for _, p := range points {
+		from, to := ownerAt(prev, p), ownerAt(next, p)
+
+		if from != to {
+			if last := len(ret) - 1; last >= 0 && ret[last].End+1 == start && ret[last].From == from && ret[last].To == to {
+				ret[last].End = p
+			} else {
+				ret = append(ret, Range{Start: start, End: p, From: from, To: to})
+			}
+		}
+
+		start = p + 1
+	}
+
+	return ret
+}
+
+// ownerAt returns the node owning the hash, "" if there are no nodes
+func ownerAt(nodes []ringNode, hash uint64) string {
+	if len(nodes) == 0 {
+		return ""
+	}
+
+	return nodes[searchNodes(nodes, hash, true)%len(nodes)].nodeName
+}
@@ -0,0 +1,207 @@
+package consistenthash
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/go-pantheon/fabrica-util/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// movedKeys applies the change and returns the owners before and after of every key that moved
+func movedKeys(t *testing.T, r *HashRing, change Change) map[string][2]string {
+	t.Helper()
+
+	const keys = 20_000
+
+	before := make([]string, keys)
+	for i := range keys {
+		before[i], _ = r.GetNode("player:" + strconv.Itoa(i))
+	}
+
+	require.NoError(t, r.Apply(change))
+
+	ret := make(map[string][2]string)
+
+	for i := range keys {
+		key := "player:" + strconv.Itoa(i)
+
+		if after, _ := r.GetNode(key); after != before[i] {
+			ret[key] = [2]string{before[i], after}
+		}
+	}
+
+	return ret
+}
+
+// rangeOf returns the owners before and after of the range containing the key, if any
+func rangeOf(r *HashRing, ranges []Range, key string) ([2]string, bool) {
+	for _, rg := range ranges {
+		if rg.Contains(r.Hash(key)) {
+			return [2]string{rg.From, rg.To}, true
+		}
+	}
+
+	return [2]string{}, false
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		change Change
+	}{
+		{"add", Change{Add: map[string]int{"node4": 1}}},
+		{"remove", Change{Remove: []string{"node2"}}},
+		{"reweight", Change{Remove: []string{"node1"}, Add: map[string]int{"node1": 3}}},
+		{"re-add", Change{Add: map[string]int{"node2": 1}}},
+		{"replace", Change{Remove: []string{"node3"}, Add: map[string]int{"node5": 1, "node6": 2}}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := NewRing(50)
+			for _, n := range []string{"node1", "node2", "node3"} {
+				require.NoError(t, r.AddNode(n))
+			}
+
+			ranges, err := r.Diff(tt.change)
+			require.NoError(t, err)
+			require.NotEmpty(t, ranges)
+			assert.Equal(t, 150, r.Len())
+
+			for i := 1; i < len(ranges); i++ {
+				assert.Less(t, ranges[i-1].End, ranges[i].Start)
+			}
+
+			moved := movedKeys(t, r, tt.change)
+			require.NotEmpty(t, moved)
+
+			for i := range 20_000 {
+				key := "player:" + strconv.Itoa(i)
+				owners, inRange := rangeOf(r, ranges, key)
+
+				if m, ok := moved[key]; ok {
+					assert.True(t, inRange, key)
+					assert.Equal(t, m, owners, key)
+				} else {
+					assert.False(t, inRange, key)
+				}
+			}
+		})
+	}
+}
+
+func TestDiffEmptyRing(t *testing.T) {
+	t.Parallel()
+
+	r := NewRing(10)
+
+	ranges, err := r.Diff(Change{Add: map[string]int{"node1": 1}})
+	require.NoError(t, err)
+	assert.Equal(t, []Range{{Start: 0, End: math.MaxUint64, To: "node1"}}, ranges)
+
+	require.NoError(t, r.AddNode("node1"))
+
+	ranges, err = r.Diff(Change{Remove: []string{"node1"}})
+	require.NoError(t, err)
+	assert.Equal(t, []Range{{Start: 0, End: math.MaxUint64, From: "node1"}}, ranges)
+
+	_, err = r.Diff(Change{Add: map[string]int{"node2": 0}})
+	assert.True(t, errors.Is(err, ErrInvalidWeight))
+}
+
+func TestSubscribe(t *testing.T) {
+	t.Parallel()
+
+	r := NewInt64Ring(20)
+	require.NoError(t, r.AddNode("node1"))
+
+	var events []Event
+
+	unsubscribe := r.Subscribe(func(e Event) {
+		events = append(events, e)
+	})
+
+	change := Change{Add: map[string]int{"node2": 1}}
+
+	ranges, err := r.Diff(change)
+	require.NoError(t, err)
+	require.NoError(t, r.AddNode("node2"))
+
+	r.RemoveNode("unknown")
+	assert.Error(t, r.AddWeightedNode("node3", 0))
+
+	require.Len(t, events, 1)
+	assert.Equal(t, change, events[0].Change)
+	assert.Equal(t, ranges, events[0].Ranges)
+
+	for _, rg := range ranges {
+		assert.Equal(t, "node1", rg.From)
+		assert.Equal(t, "node2", rg.To)
+	}
+
+	unsubscribe()
+	r.RemoveNode("node2")
+	assert.Len(t, events, 1)
+}
+
+func TestDiffReAdd(t *testing.T) {
+	t.Parallel()
+
+	r := NewRing(50)
+	for _, n := range []string{"node1", "node2"} {
+		require.NoError(t, r.AddNode(n))
+	}
+
+	change := Change{Add: map[string]int{"node1": 1}}
+
+	ranges, err := r.Diff(change)
+	require.NoError(t, err)
+	require.NotEmpty(t, ranges)
+
+	// the extra virtual nodes of node1 only take keys from node2
+	for _, rg := range ranges {
+		assert.Equal(t, "node2", rg.From)
+		assert.Equal(t, "node1", rg.To)
+	}
+
+	require.NoError(t, r.Apply(change))
+	assert.Equal(t, 150, r.Len())
+
+	ranges, err = r.Diff(Change{Remove: []string{"node1"}, Add: map[string]int{"node1": 2}})
+	require.NoError(t, err)
+	assert.Empty(t, ranges, "replacing node1 with the same weight moves nothing")
+}
+
+func TestDiffKeepsLoads(t *testing.T) {
+	t.Parallel()
+
+	r := NewRing(10, WithBoundedLoad(0.25))
+	for _, n := range []string{"a", "b"} {
+		require.NoError(t, r.AddNode(n))
+	}
+
+	for range 3 {
+		r.Inc("a")
+	}
+
+	ranges, err := r.Diff(Change{Remove: []string{"a"}})
+	require.NoError(t, err)
+	require.NotEmpty(t, ranges)
+
+	// Diff only previews the change, the loads of the live ring are untouched
+	assert.Equal(t, int64(3), r.Load("a"))
+	assert.Equal(t, int64(3), r.state.Load().loads.total.Load())
+
+	r.Inc("a")
+	assert.Equal(t, int64(4), r.Load("a"))
+	assert.Equal(t, 20, r.Len())
+}
@@ -0,0 +1,84 @@
+package consistenthash
+
+import (
+	"sync"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/spaolacci/murmur3"
+)
+
+// Hasher places the keys and the virtual nodes of a Ring on the ring
+type Hasher[K any] interface {
+	// HashKey returns the position of the key
+	HashKey(key K) uint64
+	// HashNode returns the position of a virtual node, named "<node>:<i>"
+	HashNode(name string) uint64
+}
+
+// HashFunc hashes bytes to 64 bits
+type HashFunc func(data []byte) uint64
+
+var (
+	// Murmur3 is the 64-bit MurmurHash3 hash
+	Murmur3 HashFunc = murmur3.Sum64
+	// XXHash is the 64-bit xxHash hash
+	XXHash HashFunc = xxhash.Sum64
+	// FNV is the 64-bit FNV-1a hash, it is cheap but spreads names differing by a suffix less evenly
+	FNV HashFunc = fnv1a
+)
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+func fnv1a(data []byte) uint64 {
+	h := uint64(fnvOffset64)
+	for _, b := range data {
+		h ^= uint64(b)
+		h *= fnvPrime64
+	}
+
+	return h
+}
+
+// StringHasher returns a Hasher of string keys hashing them and the node names with fn
+func StringHasher(fn HashFunc) Hasher[string] {
+	return NewHasher(fn, func(dst []byte, key string) []byte {
+		return append(dst, key...)
+	})
+}
+
+// NewHasher returns a Hasher of custom keys, such as zone IDs or composite keys
+// encode appends the bytes of the key to dst, they are hashed with fn like the node names
+func NewHasher[K any](fn HashFunc, encode func(dst []byte, key K) []byte) Hasher[K] {
+	return &funcHasher[K]{
+		fn:     fn,
+		encode: encode,
+		buffers: sync.Pool{
+			New: func() any {
+				b := make([]byte, 0, 64)
+				return &b
+			},
+		},
+	}
+}
+
+type funcHasher[K any] struct {
+	fn      HashFunc
+	encode  func(dst []byte, key K) []byte
+	buffers sync.Pool
+}
+
+func (h *funcHasher[K]) HashKey(key K) uint64 {
+	buf := h.buffers.Get().(*[]byte)
+	defer h.buffers.Put(buf)
+
+	*buf = h.encode((*buf)[:0], key)
+
+	return h.fn(*buf)
+}
+
+func (h *funcHasher[K]) HashNode(name string) uint64 {
+	return h.fn([]byte(name))
+}
@@ -0,0 +1,77 @@
+package consistenthash
+
+import (
+	"hash/fnv"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFNV(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"", "a", "node1:0", "player:123456"} {
+		h := fnv.New64a()
+		_, err := h.Write([]byte(s))
+		require.NoError(t, err)
+
+		assert.Equal(t, h.Sum64(), FNV([]byte(s)), s)
+	}
+}
+
+func TestStringHasher(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		fn    HashFunc
+		delta float64
+	}{
+		{"murmur3", Murmur3, 2_500},
+		{"xxhash", XXHash, 2_500},
+		{"fnv", FNV, 6_000},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := StringHasher(tt.fn)
+			assert.Equal(t, tt.fn([]byte("key")), h.HashKey("key"))
+			assert.Equal(t, tt.fn([]byte("node:1")), h.HashNode("node:1"))
+
+			r := New(100, h)
+			for _, n := range []string{"node1", "node2", "node3"} {
+				require.NoError(t, r.AddNode(n))
+			}
+
+			distribution := make(map[string]int)
+
+			for i := range 30_000 {
+				node, ok := r.GetNode("player:" + strconv.Itoa(i))
+				require.True(t, ok)
+
+				distribution[node]++
+			}
+
+			require.Len(t, distribution, 3)
+
+			for node, count := range distribution {
+				assert.InDelta(t, 10_000, count, tt.delta, node)
+			}
+		})
+	}
+}
+
+func TestKetamaHashersUnchanged(t *testing.T) {
+	t.Parallel()
+
+	// positions of the earlier string and int64 rings, changing them would move every key
+	assert.Equal(t, uint64(0x2c776a38), ketamaHasher{}.HashKey("node1:0"))
+	assert.Equal(t, uint64(42), int64Hasher{}.HashKey(-42))
+	assert.Equal(t, Murmur3([]byte("node1:0")), int64Hasher{}.HashNode("node1:0"))
+}
@@ -44,8 +44,8 @@ func TestInt64HashRing_AddNode(t *testing.T) {
 	err := r.AddNode(nodes[0])
 	require.Nil(t, err)
 
-	if len(r.nodes) != 100 {
-		t.Errorf("Expected 100 virtual nodes, got %d", len(r.nodes))
+	if len(r.state.Load().nodes) != 100 {
+		t.Errorf("Expected 100 virtual nodes, got %d", len(r.state.Load().nodes))
 	}
 
 	err = r.AddNode(nodes[1])
@@ -81,7 +81,7 @@ func TestInt64HashRing_RemoveNode(t *testing.T) {
 	t.Run("remove existing node", func(t *testing.T) {
 		r.RemoveNode(nodes[1])
 
-		for _, n := range r.nodes {
+		for _, n := range r.state.Load().nodes {
 			assert.NotEqual(t, n.nodeName, nodes[1])
 		}
 	})
@@ -154,7 +154,7 @@ func TestInt64HashRing_GetNode(t *testing.T) {
 	t.Run("ring wrap-around", func(t *testing.T) {
 		t.Parallel()
 		// Find the highest hash value
-		maxHash := r.nodes[r.Len()-1].hash
+		maxHash := r.state.Load().nodes[r.Len()-1].hash
 		testKey := maxHash + 1                                     // Force wrap-around
 		node, ok := r.GetNode(int64(testKey & 0x7FFFFFFFFFFFFFFF)) //nolint:gosec // Acceptable for tests
 		require.True(t, ok)
@@ -200,6 +200,36 @@ func TestInt64HashRing_ConcurrentAccess(t *testing.T) {
 	wg.Wait()
 }
 
+func TestInt64HashRing_GetNodes(t *testing.T) {
+	t.Parallel()
+
+	r := NewInt64Ring(20)
+
+	assert.Nil(t, r.GetNodes(1, 3))
+	assert.Empty(t, slices.Collect(r.Nodes(1)))
+
+	for _, n := range []string{"node1", "node2", "node3", "node4"} {
+		require.NoError(t, r.AddNode(n))
+	}
+
+	for _, key := range []int64{0, 1, -42, 1 << 40, -(1 << 62)} {
+		first, ok := r.GetNode(key)
+		require.True(t, ok)
+
+		nodes := r.GetNodes(key, 3)
+		require.Len(t, nodes, 3)
+		assert.Equal(t, first, nodes[0])
+		assert.Len(t, slices.Compact(slices.Sorted(slices.Values(nodes))), 3)
+
+		all := r.GetNodes(key, 10)
+		assert.Len(t, all, 4)
+		assert.Equal(t, nodes, all[:3])
+		assert.Equal(t, all, slices.Collect(r.Nodes(key)))
+	}
+
+	assert.Nil(t, r.GetNodes(1, 0))
+}
+
 func BenchmarkInt64HashRing_GetNode(b *testing.B) {
 	r := NewInt64Ring(160)
 	for i := range 10 {
@@ -210,8 +240,8 @@ func BenchmarkInt64HashRing_GetNode(b *testing.B) {
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
-			_, err := r.GetNode(int64(b.N))
-			require.Nil(b, err)
+			_, ok := r.GetNode(int64(b.N))
+			require.True(b, ok)
 		}
 	})
 }
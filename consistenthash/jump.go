@@ -0,0 +1,89 @@
+package consistenthash
+
+import (
+	"slices"
+	"sync"
+)
+
+// JumpHash returns the bucket of the key in [0, buckets), Lamping and Veach's jump consistent hash
+// Growing from n to n+1 buckets only moves 1/(n+1) of the keys, all of them to the new bucket
+// It returns -1 when buckets <= 0
+func JumpHash(key uint64, buckets int) int {
+	if buckets <= 0 {
+		return -1
+	}
+
+	b, j := int64(-1), int64(0)
+
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+
+	return int(b)
+}
+
+// Jump balances keys over numbered buckets with JumpHash, bucket i is the i-th node added
+// It needs no memory per key or virtual node, but only removing the last node moves the minimum
+// of keys. Removing another node moves its bucket to the last node, so the keys of both move.
+type Jump[K any] struct {
+	mu sync.RWMutex
+
+	hasher Hasher[K]
+	nodes  []string
+}
+
+// NewJump creates a jump hash balancer placing keys with the hasher
+func NewJump[K any](hasher Hasher[K]) *Jump[K] {
+	return &Jump[K]{
+		hasher: hasher,
+	}
+}
+
+// AddNode adds a node as the next bucket
+func (j *Jump[K]) AddNode(nodeName string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !slices.Contains(j.nodes, nodeName) {
+		j.nodes = append(j.nodes, nodeName)
+	}
+
+	return nil
+}
+
+// RemoveNode removes a node, the last node takes its bucket
+func (j *Jump[K]) RemoveNode(nodeName string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	i := slices.Index(j.nodes, nodeName)
+	if i < 0 {
+		return
+	}
+
+	last := len(j.nodes) - 1
+	j.nodes[i] = j.nodes[last]
+	j.nodes = j.nodes[:last]
+}
+
+// GetNode returns the node of the bucket of the key
+func (j *Jump[K]) GetNode(key K) (string, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	if len(j.nodes) == 0 {
+		return "", false
+	}
+
+	return j.nodes[JumpHash(j.hasher.HashKey(key), len(j.nodes))], true
+}
+
+// Buckets returns the nodes in bucket order
+func (j *Jump[K]) Buckets() []string {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return slices.Clone(j.nodes)
+}
@@ -0,0 +1,57 @@
+package consistenthash
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/go-pantheon/fabrica-util/internal/hashmix"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJumpHash(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, -1, JumpHash(1, 0))
+	assert.Equal(t, 0, JumpHash(1, 1))
+
+	// growing the buckets only moves keys to the new bucket
+	for key := range uint64(2000) {
+		key := hashmix.Mix64(key)
+		prev := JumpHash(key, 1)
+
+		for n := 2; n <= 64; n++ {
+			b := JumpHash(key, n)
+			require.True(t, b >= 0 && b < n)
+
+			if b != prev {
+				assert.Equal(t, n-1, b)
+			}
+
+			prev = b
+		}
+	}
+}
+
+func TestJump(t *testing.T) {
+	t.Parallel()
+
+	j := NewJump(StringHasher(Murmur3))
+	for _, n := range []string{"shard0", "shard1", "shard2", "shard1"} {
+		require.NoError(t, j.AddNode(n))
+	}
+
+	assert.Equal(t, []string{"shard0", "shard1", "shard2"}, j.Buckets())
+
+	for i := range 100 {
+		key := "key" + strconv.Itoa(i)
+
+		node, ok := j.GetNode(key)
+		require.True(t, ok)
+		assert.Equal(t, "shard"+strconv.Itoa(JumpHash(Murmur3([]byte(key)), 3)), node)
+	}
+
+	// the last node takes the bucket of a removed node
+	j.RemoveNode("shard0")
+	assert.Equal(t, []string{"shard2", "shard1"}, j.Buckets())
+}
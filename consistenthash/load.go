@@ -0,0 +1,160 @@
+package consistenthash
+
+import (
+	"maps"
+	"math"
+	"sync/atomic"
+
+	"github.com/go-pantheon/fabrica-util/errors"
+)
+
+// ErrInvalidWeight is returned when a node is added with a weight below 1
+var ErrInvalidWeight = errors.New("consistenthash: node weight must be positive")
+
+// Option define the type of the ring option function
+type Option func(*options)
+
+type options struct {
+	epsilon float64
+}
+
+// WithBoundedLoad enables consistent hashing with bounded loads
+// GetNode skips the nodes whose load reported through Inc and Done would exceed (1+epsilon) times
+// their fair share, and returns the next node clockwise instead. Smaller epsilon balances better
+// and moves more keys away from their first choice.
+func WithBoundedLoad(epsilon float64) Option {
+	return func(o *options) {
+		if epsilon > 0 {
+			o.epsilon = epsilon
+		}
+	}
+}
+
+// removedCount marks the counter of a removed node, which older snapshots may still reach
+const removedCount = math.MinInt64
+
+// loads tracks the weights and the reported loads of the nodes of a ring
+// The maps belong to a ring snapshot and aren't modified once it is published, the counters are
+// atomic and shared with the next snapshots, so that Inc and Done need no lock
+type loads struct {
+	epsilon     float64
+	weights     map[string]int
+	totalWeight int
+	counts      map[string]*atomic.Int64
+	total       *atomic.Int64
+}
+
+func newLoads(opts ...Option) loads {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return loads{
+		epsilon: o.epsilon,
+		weights: make(map[string]int),
+		counts:  make(map[string]*atomic.Int64),
+		total:   &atomic.Int64{},
+	}
+}
+
+// apply returns the loads with the change applied, l is left as is
+func (l loads) apply(change Change) loads {
+	l.weights = maps.Clone(l.weights)
+	l.counts = maps.Clone(l.counts)
+
+	for _, nodeName := range change.Remove {
+		l.remove(nodeName)
+	}
+
+	for nodeName, weight := range change.Add {
+		l.add(nodeName, weight)
+	}
+
+	return l
+}
+
+func (l *loads) add(nodeName string, weight int) {
+	if _, ok := l.counts[nodeName]; !ok {
+		l.counts[nodeName] = &atomic.Int64{}
+	}
+
+	l.weights[nodeName] += weight
+	l.totalWeight += weight
+}
+
+// remove drops the node and its load from the total
+// Its counter is marked removed rather than read, so that an Inc racing through an older snapshot
+// either lands before the swap and is subtracted with it, or sees the mark and is ignored
+func (l *loads) remove(nodeName string) {
+	if count, ok := l.counts[nodeName]; ok {
+		if c := count.Swap(removedCount); c > 0 {
+			l.total.Add(-c)
+		}
+	}
+
+	l.totalWeight -= l.weights[nodeName]
+	delete(l.weights, nodeName)
+	delete(l.counts, nodeName)
+}
+
+// inc adds one to the load of the node, unknown or removed nodes are ignored
+func (l *loads) inc(nodeName string) {
+	count, ok := l.counts[nodeName]
+	if !ok {
+		return
+	}
+
+	for {
+		c := count.Load()
+		if c == removedCount {
+			return
+		}
+
+		if count.CompareAndSwap(c, c+1) {
+			l.total.Add(1)
+			return
+		}
+	}
+}
+
+// done removes one from the load of the node, unknown, removed or idle nodes are ignored
+func (l *loads) done(nodeName string) {
+	count, ok := l.counts[nodeName]
+	if !ok {
+		return
+	}
+
+	for {
+		c := count.Load()
+		if c <= 0 {
+			return
+		}
+
+		if count.CompareAndSwap(c, c-1) {
+			l.total.Add(-1)
+			return
+		}
+	}
+}
+
+func (l *loads) load(nodeName string) int64 {
+	if count, ok := l.counts[nodeName]; ok {
+		return max(count.Load(), 0)
+	}
+
+	return 0
+}
+
+// accept reports whether the node can take one more load
+// Its capacity is ceil((1+epsilon) * (total+1) * weight / totalWeight), so at least one node always accepts
+func (l *loads) accept(nodeName string) bool {
+	if l.epsilon == 0 || l.totalWeight == 0 {
+		return true
+	}
+
+	share := float64(l.total.Load()+1) * float64(l.weights[nodeName]) / float64(l.totalWeight)
+	capacity := int64(math.Ceil((1 + l.epsilon) * share))
+
+	return l.load(nodeName) < capacity
+}
@@ -0,0 +1,248 @@
+package consistenthash
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/go-pantheon/fabrica-util/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWeightedNodes(t *testing.T) {
+	t.Parallel()
+
+	const keys = 40_000
+
+	t.Run("string ring", func(t *testing.T) {
+		t.Parallel()
+
+		r := NewRing(100)
+		require.NoError(t, r.AddNode("small"))
+		require.NoError(t, r.AddWeightedNode("large", 3))
+		assert.Equal(t, 400, r.Len())
+
+		distribution := make(map[string]int)
+
+		for i := range keys {
+			node, ok := r.GetNode("player:" + strconv.Itoa(i))
+			require.True(t, ok)
+
+			distribution[node]++
+		}
+
+		assert.InDelta(t, 3.0, float64(distribution["large"])/float64(distribution["small"]), 0.6)
+	})
+
+	t.Run("int64 ring", func(t *testing.T) {
+		t.Parallel()
+
+		r := NewInt64Ring(100)
+		require.NoError(t, r.AddNode("small"))
+		require.NoError(t, r.AddWeightedNode("large", 3))
+		assert.Equal(t, 400, r.Len())
+
+		distribution := make(map[string]int)
+
+		// the int64 ring places keys by value, spread them over the whole range
+		for i := range int64(keys) {
+			node, ok := r.GetNode(i * (math.MaxInt64 / keys))
+			require.True(t, ok)
+
+			distribution[node]++
+		}
+
+		assert.InDelta(t, 3.0, float64(distribution["large"])/float64(distribution["small"]), 0.6)
+	})
+
+	t.Run("re-added node", func(t *testing.T) {
+		t.Parallel()
+
+		twice := NewRing(100)
+		require.NoError(t, twice.AddNode("a"))
+		require.NoError(t, twice.AddNode("a"))
+		require.NoError(t, twice.AddWeightedNode("b", 2))
+		assert.Equal(t, 400, twice.Len())
+
+		weighted := NewRing(100)
+		require.NoError(t, weighted.AddWeightedNode("a", 2))
+		require.NoError(t, weighted.AddWeightedNode("b", 2))
+
+		distribution := make(map[string]int)
+
+		for i := range keys {
+			key := "player:" + strconv.Itoa(i)
+
+			node, ok := twice.GetNode(key)
+			require.True(t, ok)
+
+			// adding a node twice places it exactly like adding it with weight 2
+			expected, _ := weighted.GetNode(key)
+			require.Equal(t, expected, node)
+
+			distribution[node]++
+		}
+
+		assert.InDelta(t, 1.0, float64(distribution["a"])/float64(distribution["b"]), 0.2)
+	})
+
+	t.Run("invalid weight", func(t *testing.T) {
+		t.Parallel()
+
+		assert.True(t, errors.Is(NewRing(10).AddWeightedNode("node", 0), ErrInvalidWeight))
+		assert.True(t, errors.Is(NewInt64Ring(10).AddWeightedNode("node", -1), ErrInvalidWeight))
+	})
+}
+
+func TestBoundedLoad(t *testing.T) {
+	t.Parallel()
+
+	const (
+		epsilon = 0.25
+		keys    = 3000
+	)
+
+	nodes := []string{"gate1", "gate2", "gate3"}
+
+	t.Run("string ring", func(t *testing.T) {
+		t.Parallel()
+
+		r := NewRing(10, WithBoundedLoad(epsilon))
+		for _, n := range nodes {
+			require.NoError(t, r.AddNode(n))
+		}
+
+		for i := range keys {
+			node, ok := r.GetNode("session:" + strconv.Itoa(i))
+			require.True(t, ok)
+			r.Inc(node)
+		}
+
+		for _, n := range nodes {
+			assert.LessOrEqual(t, r.Load(n), int64(math.Ceil((1+epsilon)*keys/3)))
+		}
+	})
+
+	t.Run("int64 ring", func(t *testing.T) {
+		t.Parallel()
+
+		r := NewInt64Ring(10, WithBoundedLoad(epsilon))
+		for _, n := range nodes {
+			require.NoError(t, r.AddNode(n))
+		}
+
+		// small keys all hash before the first virtual node, without bounds they would all go to one node
+		for i := range int64(keys) {
+			node, ok := r.GetNode(i)
+			require.True(t, ok)
+			r.Inc(node)
+		}
+
+		for _, n := range nodes {
+			assert.LessOrEqual(t, r.Load(n), int64(math.Ceil((1+epsilon)*keys/3)))
+			assert.Greater(t, r.Load(n), int64(0))
+		}
+	})
+
+	t.Run("unbounded", func(t *testing.T) {
+		t.Parallel()
+
+		r := NewInt64Ring(10)
+		for _, n := range nodes {
+			require.NoError(t, r.AddNode(n))
+		}
+
+		first, _ := r.GetNode(0)
+
+		for i := range int64(keys) {
+			node, _ := r.GetNode(i)
+			r.Inc(node)
+		}
+
+		assert.Equal(t, int64(keys), r.Load(first))
+	})
+}
+
+func TestIncDone(t *testing.T) {
+	t.Parallel()
+
+	r := NewRing(10, WithBoundedLoad(0.1))
+	require.NoError(t, r.AddNode("a"))
+	require.NoError(t, r.AddWeightedNode("b", 2))
+
+	r.Inc("a")
+	r.Inc("a")
+	r.Inc("b")
+	r.Inc("ghost")
+	assert.Equal(t, int64(2), r.Load("a"))
+	assert.Equal(t, int64(3), r.state.Load().loads.total.Load())
+
+	r.Done("a")
+	r.Done("b")
+	r.Done("b")
+	r.Done("ghost")
+	assert.Equal(t, int64(1), r.Load("a"))
+	assert.Equal(t, int64(0), r.Load("b"), "Done must not go below 0")
+	assert.Equal(t, int64(1), r.state.Load().loads.total.Load())
+
+	r.RemoveNode("a")
+	assert.Equal(t, int64(0), r.Load("a"))
+	assert.Equal(t, int64(0), r.state.Load().loads.total.Load())
+	assert.Equal(t, 2, r.state.Load().loads.totalWeight)
+}
+
+func TestIncOnStaleSnapshot(t *testing.T) {
+	t.Parallel()
+
+	r := NewRing(10, WithBoundedLoad(0.1))
+	require.NoError(t, r.AddNode("a"))
+	require.NoError(t, r.AddNode("b"))
+
+	r.Inc("a")
+	r.Inc("b")
+
+	// a lookup that loaded the snapshot before the removal reports its load afterwards
+	stale := r.state.Load()
+	r.RemoveNode("a")
+	stale.loads.inc("a")
+	stale.loads.done("a")
+
+	assert.Equal(t, int64(1), r.state.Load().loads.total.Load())
+	assert.Equal(t, int64(0), stale.loads.load("a"))
+
+	// the node comes back with a fresh counter
+	require.NoError(t, r.AddNode("a"))
+	r.Inc("a")
+	assert.Equal(t, int64(1), r.Load("a"))
+	assert.Equal(t, int64(2), r.state.Load().loads.total.Load())
+}
+
+func TestIncRacingRemove(t *testing.T) {
+	t.Parallel()
+
+	r := NewRing(10, WithBoundedLoad(0.1))
+	require.NoError(t, r.AddNode("b"))
+
+	for range 100 {
+		require.NoError(t, r.AddNode("a"))
+
+		var wg sync.WaitGroup
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for range 50 {
+				r.Inc("a")
+			}
+		}()
+
+		r.RemoveNode("a")
+		wg.Wait()
+	}
+
+	assert.Equal(t, int64(0), r.state.Load().loads.total.Load())
+}
@@ -0,0 +1,132 @@
+package consistenthash
+
+import (
+	"math/big"
+	"slices"
+	"sync"
+
+	"github.com/go-pantheon/fabrica-util/internal/hashmix"
+)
+
+// DefaultMaglevTableSize is the default size of the Maglev lookup table, a prime
+const DefaultMaglevTableSize = 65537
+
+// Maglev balances keys with the lookup table of Google's Maglev load balancer
+// A lookup is one hash and one table access, every node owns nearly the same number of entries,
+// and a membership change moves few keys besides those of the changed node. The table is rebuilt
+// on every change in O(table size), so it suits node sets that change rarely.
+type Maglev[K any] struct {
+	mu sync.RWMutex
+
+	hasher Hasher[K]
+	size   uint64
+	nodes  []string
+	table  []int32
+}
+
+// NewMaglev creates a Maglev balancer placing keys with the hasher
+// tableSize is rounded up to a prime, and should be much larger than the number of nodes, such
+// as 100 times, for an even balance. tableSize <= 0 uses DefaultMaglevTableSize.
+func NewMaglev[K any](tableSize int, hasher Hasher[K]) *Maglev[K] {
+	if tableSize <= 0 {
+		tableSize = DefaultMaglevTableSize
+	}
+
+	return &Maglev[K]{
+		hasher: hasher,
+		size:   nextPrime(uint64(tableSize)),
+	}
+}
+
+// AddNode adds a node and rebuilds the table
+func (m *Maglev[K]) AddNode(nodeName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if i, found := slices.BinarySearch(m.nodes, nodeName); !found {
+		m.nodes = slices.Insert(m.nodes, i, nodeName)
+		m.populate()
+	}
+
+	return nil
+}
+
+// RemoveNode removes a node and rebuilds the table
+func (m *Maglev[K]) RemoveNode(nodeName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if i, found := slices.BinarySearch(m.nodes, nodeName); found {
+		m.nodes = slices.Delete(m.nodes, i, i+1)
+		m.populate()
+	}
+}
+
+// GetNode returns the node of the table entry of the key
+func (m *Maglev[K]) GetNode(key K) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.nodes) == 0 {
+		return "", false
+	}
+
+	return m.nodes[m.table[hashmix.Mix64(m.hasher.HashKey(key))%m.size]], true
+}
+
+// TableSize returns the size of the lookup table
+func (m *Maglev[K]) TableSize() int {
+	return int(m.size)
+}
+
+// populate fills the table, every node claims in turn the next free entry of its own permutation
+// The nodes are sorted by name, so the table doesn't depend on the order they were added in
+func (m *Maglev[K]) populate() {
+	if len(m.nodes) == 0 {
+		m.table = nil
+		return
+	}
+
+	offsets := make([]uint64, len(m.nodes))
+	skips := make([]uint64, len(m.nodes))
+	next := make([]uint64, len(m.nodes))
+
+	for i, name := range m.nodes {
+		h := m.hasher.HashNode(name)
+		offsets[i] = h % m.size
+		skips[i] = hashmix.Mix64(h)%(m.size-1) + 1
+	}
+
+	table := make([]int32, m.size)
+	for i := range table {
+		table[i] = -1
+	}
+
+	for filled := uint64(0); ; {
+		for i := range m.nodes {
+			c := (offsets[i] + next[i]*skips[i]) % m.size
+			for table[c] >= 0 {
+				next[i]++
+				c = (offsets[i] + next[i]*skips[i]) % m.size
+			}
+
+			table[c] = int32(i) //nolint:gosec // the node count is far below the table size
+			next[i]++
+
+			if filled++; filled == m.size {
+				m.table = table
+				return
+			}
+		}
+	}
+}
+
+// nextPrime returns the smallest prime >= n
+func nextPrime(n uint64) uint64 {
+	n = max(n, 2)
+	for !new(big.Int).SetUint64(n).ProbablyPrime(0) {
+		n++
+	}
+
+	return n
+}
@@ -0,0 +1,54 @@
+package consistenthash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaglevTable(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, DefaultMaglevTableSize, NewMaglev(0, StringHasher(XXHash)).TableSize())
+	assert.Equal(t, 101, NewMaglev(100, StringHasher(XXHash)).TableSize())
+
+	m := NewMaglev(1000, StringHasher(XXHash))
+	nodes := []string{"node1", "node2", "node3", "node4", "node5", "node6", "node7"}
+
+	for _, n := range nodes {
+		require.NoError(t, m.AddNode(n))
+	}
+
+	require.NoError(t, m.AddNode("node3"))
+	require.Len(t, m.table, m.TableSize())
+
+	// every node owns nearly the same number of entries
+	entries := make(map[int32]int)
+	for _, i := range m.table {
+		entries[i]++
+	}
+
+	require.Len(t, entries, len(nodes))
+
+	for _, n := range entries {
+		assert.InDelta(t, m.TableSize()/len(nodes), n, 1)
+	}
+
+	// the table doesn't depend on the order the nodes were added in
+	other := NewMaglev(1000, StringHasher(XXHash))
+	for i := len(nodes) - 1; i >= 0; i-- {
+		require.NoError(t, other.AddNode(nodes[i]))
+	}
+
+	assert.Equal(t, m.table, other.table)
+
+	for _, n := range nodes {
+		m.RemoveNode(n)
+	}
+
+	assert.Nil(t, m.table)
+
+	_, ok := m.GetNode("key")
+	assert.False(t, ok)
+}
@@ -0,0 +1,189 @@
+// Package membership keeps consistent hash rings in sync with a registry shared by the fleet
+// Every process announces itself with Heartbeat and follows the members with a Syncer, so that
+// all rings hold the same nodes instead of each process calling AddNode itself
+package membership
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-pantheon/fabrica-util/consistenthash"
+	"github.com/go-pantheon/fabrica-util/errors"
+)
+
+const (
+	// DefaultInterval is the default interval between two syncs of a Syncer without notifications
+	DefaultInterval = 5 * time.Second
+	// DefaultHeartbeatInterval is the interval Heartbeat uses when given none, a third of the default redis registry TTL
+	DefaultHeartbeatInterval = 5 * time.Second
+)
+
+// Member is a node of the ring with its weight
+type Member struct {
+	Name   string
+	Weight int
+}
+
+// Registry stores the members shared by every process, such as redismembership.Registry
+type Registry interface {
+	// Register adds the member or refreshes it, it expires after the registry TTL unless registered again
+	Register(ctx context.Context, m Member) error
+	// Deregister removes the member at once
+	Deregister(ctx context.Context, name string) error
+	// Members returns the live members
+	Members(ctx context.Context) ([]Member, error)
+	// Watch returns a channel receiving a value when the members may have changed, it is closed
+	// once ctx is done. Notifications may be coalesced or lost, Syncer polls as well.
+	Watch(ctx context.Context) (<-chan struct{}, error)
+}
+
+// Ring is the membership side of consistenthash.Ring, for rings of any key type
+type Ring interface {
+	Apply(change consistenthash.Change) error
+}
+
+// Syncer applies the members of a registry to a ring
+// It only changes the nodes it added itself, the ring should not be changed by other means
+type Syncer struct {
+	registry Registry
+	ring     Ring
+	interval time.Duration
+
+	mu      sync.Mutex
+	current map[string]int
+}
+
+// Option define the type of the syncer option function
+type Option func(*Syncer)
+
+// WithInterval set the interval between two syncs without notifications
+func WithInterval(d time.Duration) Option {
+	return func(s *Syncer) {
+		if d > 0 {
+			s.interval = d
+		}
+	}
+}
+
+// NewSyncer creates a syncer applying the members of the registry to the ring
+func NewSyncer(registry Registry, ring Ring, opts ...Option) *Syncer {
+	s := &Syncer{
+		registry: registry,
+		ring:     ring,
+		interval: DefaultInterval,
+		current:  make(map[string]int),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Sync reads the members once and applies the difference to the ring as a single change
+func (s *Syncer) Sync(ctx context.Context) error {
+	members, err := s.registry.Members(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change := consistenthash.Change{Add: make(map[string]int)}
+	next := make(map[string]int, len(members))
+
+	for _, m := range members {
+		next[m.Name] = m.Weight
+
+		if weight, ok := s.current[m.Name]; !ok {
+			change.Add[m.Name] = m.Weight
+		} else if weight != m.Weight {
+			change.Remove = append(change.Remove, m.Name)
+			change.Add[m.Name] = m.Weight
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(s.current)) {
+		if _, ok := next[name]; !ok {
+			change.Remove = append(change.Remove, name)
+		}
+	}
+
+	if len(change.Add) == 0 && len(change.Remove) == 0 {
+		return nil
+	}
+
+	if err = s.ring.Apply(change); err != nil {
+		return errors.Wrap(err, "apply membership change failed")
+	}
+
+	s.current = next
+
+	return nil
+}
+
+// Run syncs at once, then on every registry notification and every interval until ctx is done
+// Failed syncs are logged and retried. It returns nil once ctx is done, or the error of Watch.
+func (s *Syncer) Run(ctx context.Context) error {
+	notifications, err := s.registry.Watch(ctx)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err = s.Sync(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("membership sync failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case _, ok := <-notifications:
+			if !ok {
+				notifications = nil
+			}
+		}
+	}
+}
+
+// Heartbeat registers the member every interval until ctx is done, then deregisters it
+// interval should be well below the registry TTL, such as a third of it, interval <= 0 uses
+// DefaultHeartbeatInterval. Only the first registration error is returned, later ones are logged
+// and retried at the next beat.
+func Heartbeat(ctx context.Context, registry Registry, m Member, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultHeartbeatInterval
+	}
+
+	if err := registry.Register(ctx, m); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// deregister at once instead of waiting for the TTL, even though ctx is done
+			dctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), interval)
+			defer cancel()
+
+			return registry.Deregister(dctx, m.Name)
+		case <-ticker.C:
+			if err := registry.Register(ctx, m); err != nil && ctx.Err() == nil {
+				slog.Error("membership heartbeat failed", "member", m.Name, "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package membership
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-pantheon/fabrica-util/consistenthash"
+	"github.com/go-pantheon/fabrica-util/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRegistry is an in-process Registry without expiry
+type memoryRegistry struct {
+	mu       sync.Mutex
+	members  map[string]int
+	watchers []chan struct{}
+	err      error
+}
+
+func newMemoryRegistry() *memoryRegistry {
+	return &memoryRegistry{members: make(map[string]int)}
+}
+
+func (r *memoryRegistry) Register(_ context.Context, m Member) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+
+	r.members[m.Name] = m.Weight
+	r.notify()
+
+	return nil
+}
+
+func (r *memoryRegistry) Deregister(_ context.Context, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.members, name)
+	r.notify()
+
+	return nil
+}
+
+func (r *memoryRegistry) Members(context.Context) ([]Member, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	ret := make([]Member, 0, len(r.members))
+	for name, weight := range r.members {
+		ret = append(ret, Member{Name: name, Weight: weight})
+	}
+
+	return ret, nil
+}
+
+func (r *memoryRegistry) Watch(context.Context) (<-chan struct{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ch := make(chan struct{}, 1)
+	r.watchers = append(r.watchers, ch)
+
+	return ch, nil
+}
+
+func (r *memoryRegistry) notify() {
+	for _, ch := range r.watchers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (r *memoryRegistry) setErr(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.err = err
+}
+
+func ringNodes(r *consistenthash.HashRing) []string {
+	return slices.Sorted(slices.Values(r.GetNodes("key", r.Len())))
+}
+
+func TestSyncer(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	registry := newMemoryRegistry()
+	ring := consistenthash.NewRing(10)
+	s := NewSyncer(registry, ring)
+
+	var events []consistenthash.Event
+
+	ring.Subscribe(func(e consistenthash.Event) {
+		events = append(events, e)
+	})
+
+	require.NoError(t, registry.Register(ctx, Member{Name: "node1", Weight: 1}))
+	require.NoError(t, registry.Register(ctx, Member{Name: "node2", Weight: 2}))
+	require.NoError(t, s.Sync(ctx))
+	assert.Equal(t, []string{"node1", "node2"}, ringNodes(ring))
+	assert.Equal(t, 30, ring.Len())
+
+	// nothing changed, nothing applied
+	require.NoError(t, s.Sync(ctx))
+	assert.Len(t, events, 1)
+
+	require.NoError(t, registry.Deregister(ctx, "node1"))
+	require.NoError(t, registry.Register(ctx, Member{Name: "node2", Weight: 1}))
+	require.NoError(t, registry.Register(ctx, Member{Name: "node3", Weight: 1}))
+	require.NoError(t, s.Sync(ctx))
+	assert.Equal(t, []string{"node2", "node3"}, ringNodes(ring))
+	assert.Equal(t, 20, ring.Len())
+
+	require.Len(t, events, 2)
+	assert.ElementsMatch(t, []string{"node1", "node2"}, events[1].Change.Remove)
+	assert.Equal(t, map[string]int{"node2": 1, "node3": 1}, events[1].Change.Add)
+
+	registry.setErr(errors.New("registry down"))
+	require.Error(t, s.Sync(ctx))
+	assert.Equal(t, 20, ring.Len())
+}
+
+func TestSyncerInvalidWeight(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	registry := newMemoryRegistry()
+	ring := consistenthash.NewRing(10)
+	s := NewSyncer(registry, ring)
+
+	require.NoError(t, registry.Register(ctx, Member{Name: "node1", Weight: 0}))
+	assert.True(t, errors.Is(s.Sync(ctx), consistenthash.ErrInvalidWeight))
+	assert.Equal(t, 0, ring.Len())
+
+	// the failed change is retried at the next sync
+	require.NoError(t, registry.Register(ctx, Member{Name: "node1", Weight: 1}))
+	require.NoError(t, s.Sync(ctx))
+	assert.Equal(t, 10, ring.Len())
+}
+
+func TestRunAndHeartbeat(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	registry := newMemoryRegistry()
+	rings := []*consistenthash.Int64HashRing{consistenthash.NewInt64Ring(10), consistenthash.NewInt64Ring(10)}
+
+	var wg sync.WaitGroup
+
+	for _, ring := range rings {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			assert.NoError(t, NewSyncer(registry, ring, WithInterval(time.Hour)).Run(ctx))
+		}()
+	}
+
+	beatCtx, stop := context.WithCancel(ctx)
+	beatDone := make(chan error, 3)
+
+	for i := range 3 {
+		go func() {
+			beatDone <- Heartbeat(beatCtx, registry, Member{Name: "node" + strconv.Itoa(i), Weight: 1}, time.Hour)
+		}()
+	}
+
+	// the notifications drive the syncers, the interval is too long to matter
+	for _, ring := range rings {
+		assert.Eventually(t, func() bool { return ring.Len() == 30 }, time.Second, time.Millisecond)
+	}
+
+	for i := range int64(100) {
+		a, _ := rings[0].GetNode(i)
+		b, _ := rings[1].GetNode(i)
+		assert.Equal(t, a, b)
+	}
+
+	stop()
+
+	for range 3 {
+		require.NoError(t, <-beatDone)
+	}
+
+	for _, ring := range rings {
+		assert.Eventually(t, func() bool { return ring.Len() == 0 }, time.Second, time.Millisecond)
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+func TestHeartbeatDefaultInterval(t *testing.T) {
+	t.Parallel()
+
+	registry := newMemoryRegistry()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	// a zero interval falls back to the default instead of panicking in time.NewTicker
+	go func() {
+		done <- Heartbeat(ctx, registry, Member{Name: "node1", Weight: 1}, 0)
+	}()
+
+	assert.Eventually(t, func() bool {
+		members, err := registry.Members(ctx)
+		return err == nil && len(members) == 1
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	members, err := registry.Members(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, members)
+}
@@ -0,0 +1,194 @@
+// Package redismembership provides a Redis-backed membership.Registry
+// Members live in a sorted set scored by their expiry time, with their weights in a hash, and
+// every change is published on a channel so that the syncers of the fleet react at once
+package redismembership
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-pantheon/fabrica-util/consistenthash"
+	"github.com/go-pantheon/fabrica-util/consistenthash/membership"
+	"github.com/go-pantheon/fabrica-util/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultTTL is the default time a member stays registered without heartbeat
+const DefaultTTL = 15 * time.Second
+
+var (
+	// registerScript sets the expiry time ARGV[1] and the weight ARGV[3] of the member ARGV[2],
+	// and publishes on ARGV[4] when the member is new or its weight changed
+	registerScript = redis.NewScript(`
+local added = redis.call('ZADD', KEYS[1], ARGV[1], ARGV[2])
+local weight = redis.call('HGET', KEYS[2], ARGV[2])
+redis.call('HSET', KEYS[2], ARGV[2], ARGV[3])
+if added == 1 or weight ~= ARGV[3] then
+	redis.call('PUBLISH', ARGV[4], ARGV[2])
+end
+return added
+`)
+
+	// deregisterScript removes the member ARGV[1] and publishes on ARGV[2] if it was registered
+	deregisterScript = redis.NewScript(`
+local removed = redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+if removed == 1 then
+	redis.call('PUBLISH', ARGV[2], ARGV[1])
+end
+return removed
+`)
+
+	// membersScript evicts the members expired at ARGV[1], publishing on ARGV[2] if any, and
+	// returns the live members followed by their weight
+	membersScript = redis.NewScript(`
+local expired = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+if #expired > 0 then
+	redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+	redis.call('HDEL', KEYS[2], unpack(expired))
+	redis.call('PUBLISH', ARGV[2], 'evicted')
+end
+local ret = {}
+for _, name in ipairs(redis.call('ZRANGE', KEYS[1], 0, -1)) do
+	ret[#ret + 1] = name
+	ret[#ret + 1] = redis.call('HGET', KEYS[2], name) or '1'
+end
+return ret
+`)
+)
+
+var _ membership.Registry = (*Registry)(nil)
+
+// Registry is a membership.Registry stored in Redis under a key prefix
+// Expiry times come from the clocks of the processes, the TTL must be well above their skew
+type Registry struct {
+	client  redis.UniversalClient
+	keys    []string
+	channel string
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+// Option define the type of the registry option function
+type Option func(*Registry)
+
+// WithTTL set the time a member stays registered without heartbeat
+func WithTTL(ttl time.Duration) Option {
+	return func(r *Registry) {
+		if ttl > 0 {
+			r.ttl = ttl
+		}
+	}
+}
+
+// New creates a registry stored under key
+// The keys are {key}:members and {key}:weights, the hash tag keeps them in the same cluster slot,
+// and changes are published on {key}:changes
+func New(client redis.UniversalClient, key string, opts ...Option) *Registry {
+	tag := "{" + key + "}"
+
+	r := &Registry{
+		client:  client,
+		keys:    []string{tag + ":members", tag + ":weights"},
+		channel: tag + ":changes",
+		ttl:     DefaultTTL,
+		now:     time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Register adds the member or refreshes it for the TTL
+func (r *Registry) Register(ctx context.Context, m membership.Member) error {
+	if m.Weight < 1 {
+		return errors.Wrapf(consistenthash.ErrInvalidWeight, "member=%s weight=%d", m.Name, m.Weight)
+	}
+
+	expireAt := r.now().Add(r.ttl).UnixMilli()
+
+	err := registerScript.Run(ctx, r.client, r.keys, expireAt, m.Name, m.Weight, r.channel).Err()
+	if err != nil {
+		return errors.Wrapf(err, "redis membership register failed. key=%s member=%s", r.keys[0], m.Name)
+	}
+
+	return nil
+}
+
+// Deregister removes the member at once
+func (r *Registry) Deregister(ctx context.Context, name string) error {
+	if err := deregisterScript.Run(ctx, r.client, r.keys, name, r.channel).Err(); err != nil {
+		return errors.Wrapf(err, "redis membership deregister failed. key=%s member=%s", r.keys[0], name)
+	}
+
+	return nil
+}
+
+// Members evicts the expired members and returns the live ones
+func (r *Registry) Members(ctx context.Context) ([]membership.Member, error) {
+	values, err := membersScript.Run(ctx, r.client, r.keys, r.now().UnixMilli(), r.channel).StringSlice()
+	if err != nil {
+		return nil, errors.Wrapf(err, "redis membership members failed. key=%s", r.keys[0])
+	}
+
+	members := make([]membership.Member, 0, len(values)/2)
+
+	for i := 0; i+1 < len(values); i += 2 {
+		weight, err := strconv.Atoi(values[i+1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "redis membership weight is invalid. key=%s member=%s", r.keys[1], values[i])
+		}
+
+		members = append(members, membership.Member{Name: values[i], Weight: weight})
+	}
+
+	return members, nil
+}
+
+// Watch subscribes to the changes channel, the returned channel is closed once ctx is done
+func (r *Registry) Watch(ctx context.Context) (<-chan struct{}, error) {
+	pubsub := r.client.Subscribe(ctx, r.channel)
+
+	// wait for the subscription, so that no change published after Watch returns is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, errors.Wrapf(err, "redis membership subscribe failed. channel=%s", r.channel)
+	}
+
+	ret := make(chan struct{}, 1)
+
+	go func() {
+		defer close(ret)
+		defer func() { _ = pubsub.Close() }()
+
+		messages := pubsub.Channel()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				// coalesce the notifications the syncer hasn't consumed yet
+				select {
+				case ret <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return ret, nil
+}
+
+// Keys returns the Redis keys holding the members and their weights
+func (r *Registry) Keys() []string {
+	return r.keys
+}
@@ -0,0 +1,186 @@
+package redismembership
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-pantheon/fabrica-util/consistenthash"
+	"github.com/go-pantheon/fabrica-util/consistenthash/membership"
+	"github.com/go-pantheon/fabrica-util/errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock is a manual clock for the expiry of the members
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	_, client := newClient(t)
+	c := &clock{now: time.Unix(1_700_000_000, 0)}
+
+	r := New(client, "gates", WithTTL(10*time.Second))
+	r.now = c.Now
+
+	assert.Equal(t, []string{"{gates}:members", "{gates}:weights"}, r.Keys())
+
+	require.NoError(t, r.Register(ctx, membership.Member{Name: "gate1", Weight: 1}))
+	require.NoError(t, r.Register(ctx, membership.Member{Name: "gate2", Weight: 3}))
+	assert.True(t, errors.Is(r.Register(ctx, membership.Member{Name: "gate3"}), consistenthash.ErrInvalidWeight))
+
+	members, err := r.Members(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []membership.Member{{Name: "gate1", Weight: 1}, {Name: "gate2", Weight: 3}}, members)
+
+	// gate1 keeps beating, gate2 dies and is evicted after the TTL
+	c.Add(6 * time.Second)
+	require.NoError(t, r.Register(ctx, membership.Member{Name: "gate1", Weight: 1}))
+	c.Add(6 * time.Second)
+
+	members, err = r.Members(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []membership.Member{{Name: "gate1", Weight: 1}}, members)
+
+	require.NoError(t, r.Deregister(ctx, "gate1"))
+	require.NoError(t, r.Deregister(ctx, "unknown"))
+
+	members, err = r.Members(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, members)
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, client := newClient(t)
+	c := &clock{now: time.Unix(1_700_000_000, 0)}
+
+	r := New(client, "gates", WithTTL(time.Second))
+	r.now = c.Now
+
+	changes, err := r.Watch(ctx)
+	require.NoError(t, err)
+
+	expect := func(notified bool) {
+		t.Helper()
+
+		if notified {
+			select {
+			case <-changes:
+			case <-time.After(time.Second):
+				t.Fatal("no notification")
+			}
+
+			return
+		}
+
+		select {
+		case <-changes:
+			t.Fatal("unexpected notification")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	require.NoError(t, r.Register(ctx, membership.Member{Name: "gate1", Weight: 1}))
+	expect(true)
+
+	// a heartbeat doesn't change the members
+	require.NoError(t, r.Register(ctx, membership.Member{Name: "gate1", Weight: 1}))
+	expect(false)
+
+	require.NoError(t, r.Register(ctx, membership.Member{Name: "gate1", Weight: 2}))
+	expect(true)
+
+	c.Add(2 * time.Second)
+
+	_, err = r.Members(ctx)
+	require.NoError(t, err)
+	expect(true)
+
+	cancel()
+
+	for range changes {
+		// drain until the channel is closed once ctx is done
+	}
+}
+
+func TestSyncRings(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, client := newClient(t)
+	registry := New(client, "gates")
+
+	rings := []*consistenthash.HashRing{consistenthash.NewRing(20), consistenthash.NewRing(20)}
+
+	var wg sync.WaitGroup
+
+	for _, ring := range rings {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			assert.NoError(t, membership.NewSyncer(registry, ring).Run(ctx))
+		}()
+	}
+
+	require.NoError(t, registry.Register(ctx, membership.Member{Name: "gate1", Weight: 1}))
+	require.NoError(t, registry.Register(ctx, membership.Member{Name: "gate2", Weight: 2}))
+
+	for _, ring := range rings {
+		assert.Eventually(t, func() bool { return ring.Len() == 60 }, time.Second, time.Millisecond)
+	}
+
+	for _, key := range []string{"player:1", "player:2", "player:3"} {
+		a, _ := rings[0].GetNode(key)
+		b, _ := rings[1].GetNode(key)
+		assert.Equal(t, a, b)
+	}
+
+	require.NoError(t, registry.Deregister(ctx, "gate2"))
+
+	for _, ring := range rings {
+		assert.Eventually(t, func() bool { return ring.Len() == 20 }, time.Second, time.Millisecond)
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+func newClient(t *testing.T) (*miniredis.Miniredis, redis.UniversalClient) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return mr, client
+}
@@ -0,0 +1,105 @@
+package consistenthash
+
+import (
+	"math"
+	"sync"
+
+	"github.com/go-pantheon/fabrica-util/errors"
+	"github.com/go-pantheon/fabrica-util/internal/hashmix"
+)
+
+// Rendezvous balances keys with weighted rendezvous, or highest random weight, hashing
+// Every node scores the key and the highest score wins, so a lookup costs O(n) but needs no
+// virtual nodes, and removing a node only moves its own keys. It suits small node sets.
+type Rendezvous[K any] struct {
+	mu sync.RWMutex
+
+	hasher Hasher[K]
+	nodes  []rendezvousNode
+}
+
+type rendezvousNode struct {
+	name   string
+	hash   uint64
+	weight float64
+}
+
+// NewRendezvous creates a rendezvous hashing balancer placing keys with the hasher
+func NewRendezvous[K any](hasher Hasher[K]) *Rendezvous[K] {
+	return &Rendezvous[K]{
+		hasher: hasher,
+	}
+}
+
+// AddNode adds a node with weight 1
+func (r *Rendezvous[K]) AddNode(nodeName string) error {
+	return r.AddWeightedNode(nodeName, 1)
+}
+
+// AddWeightedNode adds a node receiving a share of the keys proportional to its weight
+// Adding an existing node updates its weight, which only moves keys from or to that node
+func (r *Rendezvous[K]) AddWeightedNode(nodeName string, weight float64) error {
+	if !(weight > 0) || math.IsInf(weight, 1) {
+		return errors.Wrapf(ErrInvalidWeight, "node=%s weight=%g", nodeName, weight)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.nodes {
+		if r.nodes[i].name == nodeName {
+			r.nodes[i].weight = weight
+			return nil
+		}
+	}
+
+	r.nodes = append(r.nodes, rendezvousNode{
+		name:   nodeName,
+		hash:   r.hasher.HashNode(nodeName),
+		weight: weight,
+	})
+
+	return nil
+}
+
+// RemoveNode removes a node, only its keys move
+func (r *Rendezvous[K]) RemoveNode(nodeName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.nodes {
+		if r.nodes[i].name == nodeName {
+			r.nodes = append(r.nodes[:i], r.nodes[i+1:]...)
+			return
+		}
+	}
+}
+
+// GetNode returns the node with the highest score for the key
+func (r *Rendezvous[K]) GetNode(key K) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.nodes) == 0 {
+		return "", false
+	}
+
+	keyHash := r.hasher.HashKey(key)
+	best, bestScore := 0, math.Inf(-1)
+
+	for i, n := range r.nodes {
+		if score := n.score(keyHash); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	return r.nodes[best].name, true
+}
+
+// score returns -weight / ln(u) with u uniform in (0, 1) drawn from the key and the node,
+// the logarithmic method gives each node a weight / total weight chance to score highest
+func (n rendezvousNode) score(keyHash uint64) float64 {
+	u := (float64(hashmix.Mix64(keyHash^n.hash)>>11) + 0.5) / (1 << 53)
+
+	return -n.weight / math.Log(u)
+}
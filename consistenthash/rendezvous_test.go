@@ -0,0 +1,56 @@
+package consistenthash
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/go-pantheon/fabrica-util/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRendezvousWeights(t *testing.T) {
+	t.Parallel()
+
+	const keys = 40_000
+
+	r := NewRendezvous(StringHasher(XXHash))
+	require.NoError(t, r.AddNode("small"))
+	require.NoError(t, r.AddWeightedNode("large", 3))
+
+	count := func() map[string]int {
+		distribution := make(map[string]int)
+
+		for i := range keys {
+			node, ok := r.GetNode("player:" + strconv.Itoa(i))
+			require.True(t, ok)
+
+			distribution[node]++
+		}
+
+		return distribution
+	}
+
+	distribution := count()
+	assert.InDelta(t, 3.0, float64(distribution["large"])/float64(distribution["small"]), 0.3)
+
+	// updating a weight only moves keys between that node and the others
+	require.NoError(t, r.AddWeightedNode("large", 1))
+
+	distribution = count()
+	assert.InDelta(t, 1.0, float64(distribution["large"])/float64(distribution["small"]), 0.1)
+}
+
+func TestRendezvousInvalidWeight(t *testing.T) {
+	t.Parallel()
+
+	r := NewRendezvous(StringHasher(XXHash))
+
+	for _, w := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		assert.True(t, errors.Is(r.AddWeightedNode("node", w), ErrInvalidWeight))
+	}
+
+	_, ok := r.GetNode("key")
+	assert.False(t, ok)
+}
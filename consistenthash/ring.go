@@ -0,0 +1,184 @@
+// Package consistenthash provides consistent hashing implementations for int64, string and custom keys
+package consistenthash
+
+import (
+	"iter"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// DefaultVirtualSpots is the default number of virtual spots per node (160)
+	DefaultVirtualSpots = 160
+)
+
+type ringNode struct {
+	nodeName string
+	hash     uint64
+}
+
+// Ring is a consistent hash ring for keys of type K, placed on the ring by its Hasher
+// HashRing and Int64HashRing are the rings of string and int64 keys
+// Lookups read an immutable snapshot of the ring without locking, membership changes build the
+// next snapshot and publish it atomically
+type Ring[K any] struct {
+	// changeMu serializes the membership changes and their notifications
+	changeMu sync.Mutex
+	state    atomic.Pointer[ringState]
+
+	hasher       Hasher[K]
+	virtualSpots int
+
+	subMu       sync.Mutex
+	subscribers map[uint64]func(Event)
+	nextID      uint64
+}
+
+// ringState is a snapshot of a ring, it is never modified once published
+type ringState struct {
+	nodes []ringNode
+	loads loads
+}
+
+// New creates a consistent hash ring with the specified number of virtual spots and hasher
+func New[K any](virtualSpots int, hasher Hasher[K], opts ...Option) *Ring[K] {
+	if virtualSpots <= 0 {
+		virtualSpots = DefaultVirtualSpots
+	}
+
+	h := &Ring[K]{
+		hasher:       hasher,
+		virtualSpots: virtualSpots,
+	}
+
+	h.state.Store(&ringState{
+		loads: newLoads(opts...),
+	})
+
+	return h
+}
+
+// AddNode add node and sort automatically
+func (h *Ring[K]) AddNode(nodeName string) error {
+	return h.AddWeightedNode(nodeName, 1)
+}
+
+// AddWeightedNode add node with weight times the virtual spots, so it receives weight times the keys
+func (h *Ring[K]) AddWeightedNode(nodeName string, weight int) error {
+	return h.Apply(Change{Add: map[string]int{nodeName: weight}})
+}
+
+// RemoveNode removes a node with the given name from the hash ring
+func (h *Ring[K]) RemoveNode(nodeName string) {
+	_ = h.Apply(Change{Remove: []string{nodeName}})
+}
+
+// GetNode returns the node name for the given key
+// It finds the closest virtual node clockwise and returns its node name
+// With WithBoundedLoad it skips overloaded nodes clockwise
+func (h *Ring[K]) GetNode(key K) (string, bool) {
+	s := h.state.Load()
+
+	if len(s.nodes) == 0 {
+		return "", false
+	}
+
+	idx := s.search(h.hasher.HashKey(key), true)
+
+	for i := range len(s.nodes) {
+		if n := s.nodes[(idx+i)%len(s.nodes)]; s.loads.accept(n.nodeName) {
+			return n.nodeName, true
+		}
+	}
+
+	return s.nodes[idx%len(s.nodes)].nodeName, true
+}
+
+// GetNodes returns up to n distinct nodes for the given key, such as the replicas of its data
+// It walks clockwise from the key and skips the virtual nodes of the nodes already chosen,
+// the first one is the node GetNode returns without bounded loads
+func (h *Ring[K]) GetNodes(key K, n int) []string {
+	s := h.state.Load()
+
+	if n <= 0 || len(s.nodes) == 0 {
+		return nil
+	}
+
+	idx := s.search(h.hasher.HashKey(key), true)
+	ret := make([]string, 0, min(n, len(s.loads.weights)))
+
+	for i := 0; i < len(s.nodes) && len(ret) < n; i++ {
+		if node := s.nodes[(idx+i)%len(s.nodes)]; !slices.Contains(ret, node.nodeName) {
+			ret = append(ret, node.nodeName)
+		}
+	}
+
+	return ret
+}
+
+// Nodes returns an iterator over the distinct nodes for the given key in GetNodes order
+// It is lazy, every step looks the next node up in the latest snapshot, so the loop body may
+// take its time, for example to fail over to the next node after a timeout
+func (h *Ring[K]) Nodes(key K) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		seen := make([]string, 0)
+		pos, inclusive := h.hasher.HashKey(key), true
+
+		for {
+			name, hash, ok := h.state.Load().next(pos, inclusive, seen)
+			if !ok || !yield(name) {
+				return
+			}
+
+			seen = append(seen, name)
+			pos, inclusive = hash, false
+		}
+	}
+}
+
+// Inc reports one more load, such as a session, on the node
+func (h *Ring[K]) Inc(nodeName string) {
+	h.state.Load().loads.inc(nodeName)
+}
+
+// Done reports the end of a load reported with Inc
+func (h *Ring[K]) Done(nodeName string) {
+	h.state.Load().loads.done(nodeName)
+}
+
+// Load returns the current load of the node
+func (h *Ring[K]) Load(nodeName string) int64 {
+	return h.state.Load().loads.load(nodeName)
+}
+
+// Len returns the number of virtual nodes on the ring
+func (h *Ring[K]) Len() int {
+	return len(h.state.Load().nodes)
+}
+
+// next returns the first node clockwise from the hash whose name isn't in seen, and the hash of its virtual node
+func (s *ringState) next(hash uint64, inclusive bool, seen []string) (string, uint64, bool) {
+	idx := s.search(hash, inclusive)
+
+	for i := range len(s.nodes) {
+		if n := s.nodes[(idx+i)%len(s.nodes)]; !slices.Contains(seen, n.nodeName) {
+			return n.nodeName, n.hash, true
+		}
+	}
+
+	return "", 0, false
+}
+
+// search returns the index of the first virtual node at or after the hash, or strictly after it
+// The index is len(s.nodes) when the hash is past the last virtual node
+func (s *ringState) search(hash uint64, inclusive bool) int {
+	return searchNodes(s.nodes, hash, inclusive)
+}
+
+func searchNodes(nodes []ringNode, hash uint64, inclusive bool) int {
+	return sort.Search(len(nodes), func(i int) bool {
+		return nodes[i].hash > hash || inclusive && nodes[i].hash == hash
+	})
+}
@@ -0,0 +1,171 @@
+package consistenthash
+
+import (
+	"encoding/binary"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type zoneKey struct {
+	zone   uint32
+	player int64
+}
+
+func encodeZoneKey(dst []byte, k zoneKey) []byte {
+	dst = binary.BigEndian.AppendUint32(dst, k.zone)
+	return binary.BigEndian.AppendUint64(dst, uint64(k.player)) //nolint:gosec // bit pattern only
+}
+
+func TestRing_CustomKey(t *testing.T) {
+	t.Parallel()
+
+	r := New(50, NewHasher(XXHash, encodeZoneKey), WithBoundedLoad(0.5))
+
+	_, ok := r.GetNode(zoneKey{zone: 1, player: 1})
+	assert.False(t, ok)
+
+	nodes := []string{"zone-a", "zone-b", "zone-c"}
+	for _, n := range nodes {
+		require.NoError(t, r.AddNode(n))
+	}
+
+	assert.Equal(t, 150, r.Len())
+
+	key := zoneKey{zone: 7, player: 1001}
+
+	node, ok := r.GetNode(key)
+	require.True(t, ok)
+	assert.Contains(t, nodes, node)
+
+	again, ok := r.GetNode(zoneKey{zone: 7, player: 1001})
+	require.True(t, ok)
+	assert.Equal(t, node, again)
+
+	replicas := r.GetNodes(key, 3)
+	assert.Equal(t, node, replicas[0])
+	assert.ElementsMatch(t, nodes, replicas)
+	assert.Equal(t, replicas, slices.Collect(r.Nodes(key)))
+
+	r.RemoveNode(node)
+
+	moved, ok := r.GetNode(key)
+	require.True(t, ok)
+	assert.Equal(t, replicas[1], moved)
+}
+
+func TestRing_DefaultVirtualSpots(t *testing.T) {
+	t.Parallel()
+
+	r := New(0, StringHasher(FNV))
+	require.NoError(t, r.AddNode("node"))
+	assert.Equal(t, DefaultVirtualSpots, r.Len())
+}
+
+// lockedRing looks keys up under a read lock, as rings did before the snapshots, for the benchmarks
+type lockedRing struct {
+	mu   sync.RWMutex
+	ring *HashRing
+}
+
+func (l *lockedRing) GetNode(key string) (string, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.ring.GetNode(key)
+}
+
+func (l *lockedRing) AddNode(nodeName string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.ring.AddNode(nodeName)
+}
+
+func (l *lockedRing) RemoveNode(nodeName string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.ring.RemoveNode(nodeName)
+}
+
+func BenchmarkRing_GetNode(b *testing.B) {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = "player:" + strconv.Itoa(i)
+	}
+
+	newRings := func(b *testing.B) map[string]Balancer[string] {
+		b.Helper()
+
+		r := NewRing(DefaultVirtualSpots)
+		for i := range 10 {
+			require.NoError(b, r.AddNode("node"+strconv.Itoa(i)))
+		}
+
+		return map[string]Balancer[string]{
+			"snapshot": r,
+			"mutex":    &lockedRing{ring: r},
+		}
+	}
+
+	for _, name := range []string{"snapshot", "mutex"} {
+		b.Run(name, func(b *testing.B) {
+			r := newRings(b)[name]
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					if _, ok := r.GetNode(keys[i%len(keys)]); !ok {
+						b.Fatal("no node")
+					}
+				}
+			})
+		})
+
+		// a membership change every millisecond, as when gateways come and go
+		b.Run(name+" with changes", func(b *testing.B) {
+			r := newRings(b)[name]
+			done := make(chan struct{})
+			stopped := make(chan struct{})
+
+			go func() {
+				defer close(stopped)
+
+				ticker := time.NewTicker(time.Millisecond)
+				defer ticker.Stop()
+
+				for i := 0; ; i++ {
+					select {
+					case <-done:
+						return
+					case <-ticker.C:
+						if i%2 == 0 {
+							_ = r.AddNode("extra")
+						} else {
+							r.RemoveNode("extra")
+						}
+					}
+				}
+			}()
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					if _, ok := r.GetNode(keys[i%len(keys)]); !ok {
+						b.Fatal("no node")
+					}
+				}
+			})
+			b.StopTimer()
+
+			close(done)
+			<-stopped
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package consistenthash
 
 import (
+	"slices"
 	"strconv"
 	"sync"
 	"testing"
@@ -165,7 +166,7 @@ func TestHashRing_GetNode(t *testing.T) {
 		// Force wrap around scenario
 		highHashKey := "zzzzzzzzzzzzzzzz"
 		node, _ := r.GetNode(highHashKey)
-		assert.Equal(t, node, r.nodes[1].nodeName)
+		assert.Equal(t, node, r.state.Load().nodes[1].nodeName)
 	})
 }
 
@@ -298,3 +299,63 @@ func BenchmarkRemoveNode(b *testing.B) {
 		r.RemoveNode("node" + strconv.Itoa(i%100))
 	}
 }
+
+func TestHashRing_GetNodes(t *testing.T) {
+	t.Parallel()
+
+	r := NewRing(20)
+
+	assert.Nil(t, r.GetNodes("key", 3))
+	assert.Empty(t, slices.Collect(r.Nodes("key")))
+
+	for _, n := range []string{"node1", "node2", "node3", "node4"} {
+		require.NoError(t, r.AddNode(n))
+	}
+
+	for i := range 100 {
+		key := "key" + strconv.Itoa(i)
+
+		first, ok := r.GetNode(key)
+		require.True(t, ok)
+
+		nodes := r.GetNodes(key, 3)
+		require.Len(t, nodes, 3)
+		assert.Equal(t, first, nodes[0])
+		assert.Len(t, slices.Compact(slices.Sorted(slices.Values(nodes))), 3)
+
+		all := r.GetNodes(key, 10)
+		assert.Len(t, all, 4)
+		assert.Equal(t, nodes, all[:3])
+		assert.Equal(t, all, slices.Collect(r.Nodes(key)))
+	}
+
+	assert.Nil(t, r.GetNodes("key", -1))
+}
+
+func TestHashRing_NodesFailover(t *testing.T) {
+	t.Parallel()
+
+	r := NewRing(20)
+	for _, n := range []string{"node1", "node2", "node3"} {
+		require.NoError(t, r.AddNode(n))
+	}
+
+	want := r.GetNodes("player:1", 3)
+	visited := make([]string, 0, 3)
+
+	// the ring may change while iterating, the lock isn't held by the loop body
+	for node := range r.Nodes("player:1") {
+		visited = append(visited, node)
+
+		if node == want[1] {
+			r.RemoveNode(want[0])
+			break
+		}
+	}
+
+	assert.Equal(t, want[:2], visited)
+
+	next, ok := r.GetNode("player:1")
+	require.True(t, ok)
+	assert.Equal(t, want[1], next)
+}
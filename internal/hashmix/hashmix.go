@@ -0,0 +1,14 @@
+// Package hashmix provides the bit mixing functions shared by the hashing packages
+package hashmix
+
+// Mix64 is the splitmix64 finalizer, it spreads the bits of x over the whole word
+// It is a bijection, so distinct inputs such as combined hashes never collide
+func Mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
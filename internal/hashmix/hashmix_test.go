@@ -0,0 +1,24 @@
+package hashmix
+
+import (
+	"math/bits"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMix64(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, uint64(0), Mix64(0))
+	assert.Equal(t, Mix64(42), Mix64(42))
+
+	// flipping one input bit flips about half of the output bits
+	flipped := 0
+
+	for i := range uint64(1000) {
+		flipped += bits.OnesCount64(Mix64(i) ^ Mix64(i^1))
+	}
+
+	assert.InDelta(t, 32, float64(flipped)/1000, 2)
+}